@@ -0,0 +1,6 @@
+package definitions
+
+type AuthContext string
+
+// AuthKey es la llave del contexto donde los middlewares de autenticación guardan el principal autenticado
+const AuthKey AuthContext = "auth"
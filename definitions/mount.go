@@ -0,0 +1,19 @@
+package definitions
+
+import (
+	"net/http"
+	"time"
+)
+
+// Mount monta un http.Handler existente (file server, pprof, otro router) bajo Prefix dentro de
+// RouteGroup.Routes. El handler atiende cualquier método y subruta del prefijo, recibe el path
+// sin el prefijo y hereda los middlewares y el timeout del grupo igual que una Route
+type Mount struct {
+	Prefix             string
+	Handler            http.Handler
+	Middlewares        *[]Middleware
+	MiddlewareParams   *map[string]interface{}
+	ExcludeMiddlewares *[]Middleware
+	// Timeout igual que en Route, un valor negativo desactiva el timeout heredado
+	Timeout time.Duration
+}
@@ -5,6 +5,9 @@ import (
 	"net/http"
 )
 
+// ResponseRecorder guarda la respuesta en memoria.
+//
+// Deprecated: CustomMuxHandler ya no lo usa, se mantiene por compatibilidad.
 type ResponseRecorder struct {
 	status      int
 	wroteHeader bool
@@ -37,13 +40,20 @@ func (r *ResponseRecorder) Write(b []byte) (int, error) {
 	return r.body.Write(b)
 }
 
+// Flush no tiene efecto, el recorder no tiene un writer de destino
+func (r *ResponseRecorder) Flush() {}
+
 func (r *ResponseRecorder) Status() int {
 	return r.status
 }
 
+// CustomMuxHandler responde con notFoundHandler las peticiones sin ruta.
+//
+// Deprecated: LoadRoutes ya registra el handler de not found (ver goroutes.SetNotFoundHandler y
+// goroutes.SetMethodNotAllowedHandler), CustomMuxHandler se mantiene por compatibilidad.
 func CustomMuxHandler(mux *http.ServeMux, notFoundHandler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		h, pattern := mux.Handler(r)
+		_, pattern := mux.Handler(r)
 
 		// Fallback: patrón "/" para paths distintos de "/" (cuando "/" actúa como catch-all)
 		if r.URL.Path != "/" && pattern == "/" {
@@ -51,24 +61,62 @@ func CustomMuxHandler(mux *http.ServeMux, notFoundHandler http.HandlerFunc) http
 			return
 		}
 
-		rr := NewResponseRecorder()
-		h.ServeHTTP(rr, r)
-		if rr.status == 0 {
-			rr.status = http.StatusOK
-		}
-
-		// Solo si NO existe ruta realmente (pattern == "") usamos notFoundHandler
-		if rr.status == http.StatusNotFound && pattern == "" {
-			notFoundHandler(w, r)
+		// con una ruta registrada la respuesta va directo al cliente, así funcionan Flush y streaming;
+		// se usa mux.ServeHTTP para que el handler reciba los path values ({id})
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
 			return
 		}
 
-		for k, vals := range rr.header {
-			for _, v := range vals {
-				w.Header().Add(k, v)
-			}
-		}
-		w.WriteHeader(rr.status)
-		_, _ = w.Write(rr.body.Bytes())
+		// sin ruta el mux responde 404 (o redirecciones/405), solo el 404 se reemplaza y sin
+		// guardar la respuesta en memoria
+		mux.ServeHTTP(&notFoundInterceptor{rw: w, r: r, notFoundHandler: notFoundHandler}, r)
+	}
+}
+
+// notFoundInterceptor reemplaza el 404 del mux por notFoundHandler y descarta el body original
+type notFoundInterceptor struct {
+	rw              http.ResponseWriter
+	r               *http.Request
+	notFoundHandler http.HandlerFunc
+	wroteHeader     bool
+	intercepted     bool
+}
+
+func (i *notFoundInterceptor) Header() http.Header {
+	return i.rw.Header()
+}
+
+func (i *notFoundInterceptor) WriteHeader(code int) {
+	if i.wroteHeader {
+		return
 	}
+	i.wroteHeader = true
+
+	if code == http.StatusNotFound {
+		i.intercepted = true
+		// headers que http.Error define para su body de texto
+		i.rw.Header().Del("Content-Type")
+		i.rw.Header().Del("X-Content-Type-Options")
+		i.notFoundHandler(i.rw, i.r)
+		return
+	}
+
+	i.rw.WriteHeader(code)
+}
+
+func (i *notFoundInterceptor) Write(b []byte) (int, error) {
+	if !i.wroteHeader {
+		i.WriteHeader(http.StatusOK)
+	}
+
+	if i.intercepted {
+		return len(b), nil
+	}
+
+	return i.rw.Write(b)
+}
+
+func (i *notFoundInterceptor) Unwrap() http.ResponseWriter {
+	return i.rw
 }
@@ -0,0 +1,22 @@
+package definitions
+
+import "time"
+
+// RouteOverride modifica rutas definidas en otros grupos (ej. las de Go desde un archivo de
+// rutas), la ruta se busca por Name y/o por Pattern ("GET /users/{id}" o "/users/{id}" para
+// todos los métodos, con el host al inicio si el grupo lo define)
+type RouteOverride struct {
+	Name    string
+	Pattern string
+	// Disabled quita la ruta
+	Disabled bool
+	// Auth reemplaza la autorización de la ruta
+	Auth *RouteAuth
+	// Middlewares se agregan al final de la cadena de la ruta y ExcludeMiddlewares se quitan
+	Middlewares        *[]Middleware
+	ExcludeMiddlewares *[]Middleware
+	// MiddlewareParams se combinan con los de la ruta, los del override tienen prioridad
+	MiddlewareParams *map[string]interface{}
+	// Timeout reemplaza el de la ruta si es distinto de 0, un valor negativo lo desactiva
+	Timeout time.Duration
+}
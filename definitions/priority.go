@@ -0,0 +1,12 @@
+package definitions
+
+// Priority define la clase de prioridad de una ruta cuando el servicio está saturado,
+// las rutas con mayor prioridad se atienden primero y las de menor prioridad se descartan antes
+type Priority int
+
+const (
+	PriorityLow      Priority = -1
+	PriorityNormal   Priority = 0
+	PriorityHigh     Priority = 1
+	PriorityCritical Priority = 2
+)
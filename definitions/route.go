@@ -1,11 +1,23 @@
 package definitions
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type RouteGroup struct {
-	Prefix      string
+	Prefix string
+	// Host limita el grupo a un host exacto ("api.example.com") o con parámetros por etiqueta
+	// ("{tenant}.example.com", disponibles con r.PathValue). Los subgrupos lo heredan
+	Host        string
 	Middlewares *[]Middleware
-	Routes      []interface{}
+	// Routes acepta Route, RouteGroup (subgrupos), Mount y VersionedGroup
+	Routes []interface{}
+	// Timeout es el tiempo máximo de ejecución por defecto de las rutas del grupo
+	Timeout time.Duration
+	// Overrides modifica o desactiva rutas de cualquier grupo pasado a LoadRoutes, solo se
+	// leen en los grupos de primer nivel
+	Overrides []RouteOverride
 }
 
 type Route struct {
@@ -17,6 +29,23 @@ type Route struct {
 	ExcludeMiddlewares *[]Middleware
 	Auth               *RouteAuth
 	Group              map[string]Route
+	// Timeout es el tiempo máximo de ejecución del handler, 0 hereda el del grupo (sin límite si no hay)
+	// y un valor negativo desactiva el timeout heredado
+	Timeout time.Duration
+	// Priority es la clase de prioridad de la ruta para el limitador de concurrencia
+	Priority Priority
+	// Pattern es la ruta completa registrada (prefijos incluidos), la asigna LoadRoutes
+	Pattern string
+	// Host es el host del grupo de la ruta, lo asigna LoadRoutes
+	Host string
+	// Version es la versión de la API de la ruta cuando pertenece a un VersionedGroup, Versions
+	// las rutas por versión del path sin versión y VersionSelector cómo se elige, las asigna LoadRoutes
+	Version         string
+	Versions        map[string]Route
+	VersionSelector *VersionSelector
+	// Name identifica la ruta para generar su URL con goroutes.URL (ej. "users.show"), es opcional
+	// y debe ser único
+	Name string
 }
 
 type RouteAuth struct {
@@ -0,0 +1,40 @@
+package definitions
+
+import "time"
+
+// VersionedGroup registra las versiones de una API bajo Prefix. Cada versión queda con su
+// prefijo (/api/v1/users) y en el path sin versión (/api/users) se elige con el header Header,
+// el parámetro version del header Accept (application/json; version=2) o Default
+type VersionedGroup struct {
+	Prefix      string
+	Middlewares *[]Middleware
+	Timeout     time.Duration
+	// Versions en orden, cada versión hereda las rutas de la anterior que no redefine
+	Versions []Version
+	// Default es la versión de las peticiones que no indican una, vacío usa la última
+	Default string
+	// Header es el header con la versión solicitada, vacío usa GOROUTES_API_VERSION_HEADER
+	Header string
+}
+
+type Version struct {
+	// Name es el segmento del path de la versión (ej. "v2"), en los headers se acepta "2" o "v2"
+	Name   string
+	Routes []interface{}
+	// Remove son las rutas heredadas que ya no existen en esta versión: "METODO /path" para una
+	// Route o el prefijo de un RouteGroup o Mount
+	Remove []string
+	// Deprecation y Sunset generan los headers Deprecation (RFC 9745) y Sunset (RFC 8594)
+	Deprecation time.Time
+	Sunset      time.Time
+	// Link es la documentación de la migración, se envía como Link con rel="deprecation"
+	Link string
+}
+
+// VersionSelector define cómo el path sin versión de un VersionedGroup elige la versión de cada
+// petición, lo asigna LoadRoutes
+type VersionSelector struct {
+	Header  string
+	Default string
+	Names   []string
+}
@@ -7,13 +7,14 @@ toolchain go1.24.5
 require (
 	github.com/Nemutagk/godb v1.4.0
 	github.com/Nemutagk/goenvars v1.4.0
+	github.com/Nemutagk/goerrors v1.2.2
+	github.com/Nemutagk/golog v1.3.10
 	github.com/gofrs/uuid v4.4.0+incompatible
 	go.mongodb.org/mongo-driver v1.17.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/Nemutagk/goerrors v1.2.2 // indirect
-	github.com/Nemutagk/golog v1.3.10 // indirect
 	github.com/aws/aws-sdk-go-v2 v1.36.3 // indirect
 	github.com/aws/aws-sdk-go-v2/config v1.29.14 // indirect
 	github.com/aws/aws-sdk-go-v2/credentials v1.17.67 // indirect
@@ -0,0 +1,222 @@
+package goroutes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/helper"
+	"github.com/Nemutagk/goroutes/lifecycle"
+	"github.com/Nemutagk/goroutes/middlewares"
+)
+
+// HealthCheck valida una dependencia del servicio, debe respetar la cancelación del contexto
+type HealthCheck func(ctx context.Context) error
+
+type healthCheckEntry struct {
+	check   HealthCheck
+	timeout time.Duration
+}
+
+type healthCheckResult struct {
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status    string                       `json:"status"`
+	Checks    map[string]healthCheckResult `json:"checks"`
+	CheckedAt time.Time                    `json:"checked_at"`
+}
+
+var (
+	healthMu        sync.Mutex
+	healthChecks    = map[string]healthCheckEntry{}
+	healthCache     *healthReport
+	healthCacheTime time.Time
+)
+
+// RegisterHealthCheck registra (o reemplaza) una validación usada por la ruta de readiness,
+// con timeout 0 se usa GOROUTES_HEALTH_CHECK_TIMEOUT
+func RegisterHealthCheck(name string, check HealthCheck, timeout time.Duration) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	healthChecks[name] = healthCheckEntry{check: check, timeout: timeout}
+	healthCache = nil
+}
+
+// MongoHealthCheck hace ping a la conexión de logs (DB_LOGS_CONNECTION)
+func MongoHealthCheck(dbConnectionsList map[string]db.DbConnection) HealthCheck {
+	return func(ctx context.Context) error {
+		conn, err := helper.DbConnections(dbConnectionsList).GetConnection(goenvars.GetEnv("DB_LOGS_CONNECTION", "logs"))
+		if err != nil {
+			return err
+		}
+
+		mongoDb, err := conn.ToMongoDb()
+		if err != nil {
+			return err
+		}
+
+		return mongoDb.Client().Ping(ctx, nil)
+	}
+}
+
+// AccountServiceHealthCheck valida que ACCOUNT_API_URL responda, la ruta se define con
+// ACCOUNT_API_HEALTH_PATH y cualquier respuesta menor a 500 se considera válida
+func AccountServiceHealthCheck() HealthCheck {
+	return func(ctx context.Context) error {
+		url := strings.TrimRight(goenvars.GetEnv("ACCOUNT_API_URL", "http://localhost:8080"), "/") + goenvars.GetEnv("ACCOUNT_API_HEALTH_PATH", "/")
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return errors.New("account service responded " + resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// HealthRoutes retorna las rutas de liveness (GOROUTES_HEALTH_PATH, /healthz) y readiness
+// (GOROUTES_READY_PATH, /readyz), no pasan por AccessMiddleware y nunca se descartan por carga
+func HealthRoutes() definitions.RouteGroup {
+	exclude := []definitions.Middleware{middlewares.AccessMiddleware}
+
+	return definitions.RouteGroup{
+		Prefix: "/",
+		Routes: []interface{}{
+			definitions.Route{
+				Path:               goenvars.GetEnv("GOROUTES_HEALTH_PATH", "/healthz"),
+				Method:             http.MethodGet,
+				Action:             livenessAction,
+				ExcludeMiddlewares: &exclude,
+				Priority:           definitions.PriorityCritical,
+			},
+			definitions.Route{
+				Path:               goenvars.GetEnv("GOROUTES_READY_PATH", "/readyz"),
+				Method:             http.MethodGet,
+				Action:             readinessAction,
+				ExcludeMiddlewares: &exclude,
+				Priority:           definitions.PriorityCritical,
+			},
+		},
+	}
+}
+
+func livenessAction(w http.ResponseWriter, r *http.Request) {
+	JsonResponse(w, map[string]any{"status": "ok"}, http.StatusOK)
+}
+
+func readinessAction(w http.ResponseWriter, r *http.Request) {
+	if !lifecycle.IsReady() {
+		JsonResponse(w, map[string]any{"status": "shutting_down"}, http.StatusServiceUnavailable)
+		return
+	}
+
+	report := runHealthChecks(r.Context())
+
+	statusCode := http.StatusOK
+	if report.Status != "ok" {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	JsonResponse(w, report, statusCode)
+}
+
+// runHealthChecks ejecuta las validaciones en paralelo, el resultado se guarda en caché por
+// GOROUTES_HEALTH_CACHE_TTL segundos para no saturar las dependencias
+func runHealthChecks(ctx context.Context) healthReport {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	ttl := time.Duration(goenvars.GetEnvInt("GOROUTES_HEALTH_CACHE_TTL", 5)) * time.Second
+	if healthCache != nil && time.Since(healthCacheTime) < ttl {
+		return *healthCache
+	}
+
+	defaultTimeout := time.Duration(goenvars.GetEnvInt("GOROUTES_HEALTH_CHECK_TIMEOUT", 2)) * time.Second
+
+	report := healthReport{Status: "ok", Checks: map[string]healthCheckResult{}, CheckedAt: time.Now()}
+	var resultMu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, entry := range healthChecks {
+		wg.Add(1)
+		go func(name string, entry healthCheckEntry) {
+			defer wg.Done()
+
+			timeout := entry.timeout
+			if timeout <= 0 {
+				timeout = defaultTimeout
+			}
+
+			checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := runHealthCheck(checkCtx, entry.check)
+
+			result := healthCheckResult{Status: "ok", DurationMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = "fail"
+				result.Error = err.Error()
+			}
+
+			resultMu.Lock()
+			defer resultMu.Unlock()
+
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = "fail"
+			}
+		}(name, entry)
+	}
+
+	wg.Wait()
+
+	healthCache = &report
+	healthCacheTime = time.Now()
+
+	return report
+}
+
+// runHealthCheck ejecuta la validación respetando el timeout aunque la función no lo haga
+func runHealthCheck(ctx context.Context, check HealthCheck) (err error) {
+	done := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("health check panic: %v", rec)
+			}
+		}()
+
+		done <- check(ctx)
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,28 @@
+package helper
+
+import (
+	"sync/atomic"
+
+	"github.com/Nemutagk/godb"
+	"github.com/Nemutagk/godb/definitions/db"
+)
+
+var dbConnectionsOpened atomic.Bool
+
+// DbConnections obtiene las conexiones con godb.InitConnections (se abren una sola vez) y registra
+// que goroutes las abrió, para que el servidor solo cierre conexiones existentes al apagarse
+func DbConnections(dbConnectionsList map[string]db.DbConnection) *godb.ConnectionManager {
+	manager := godb.InitConnections(dbConnectionsList)
+	dbConnectionsOpened.Store(true)
+
+	return manager
+}
+
+// OpenedDbConnections retorna las conexiones abiertas con DbConnections o nil si aún no se abrieron
+func OpenedDbConnections() *godb.ConnectionManager {
+	if !dbConnectionsOpened.Load() {
+		return nil
+	}
+
+	return godb.InitConnections(nil)
+}
@@ -0,0 +1,362 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+type binaryVector struct {
+	value    any
+	expected string
+}
+
+type nestedPayload struct {
+	Name     string          `json:"name"`
+	Tags     []string        `json:"tags"`
+	Owner    *nestedPayload  `json:"owner"`
+	Limits   map[string]int  `json:"limits"`
+	Children []nestedPayload `json:"children,omitempty"`
+	Enabled  bool            `json:"enabled"`
+	Ratio    float64         `json:"ratio"`
+}
+
+var roundTripValues = []any{
+	nil,
+	true,
+	false,
+	0, 23, 24, 127, 128, 255, 256, 32767, 32768, 65535, 65536,
+	math.MaxInt32, math.MaxInt32 + 1, math.MaxUint32, math.MaxUint32 + 1, int64(math.MaxInt64), uint64(math.MaxUint64),
+	-1, -24, -25, -32, -33, -128, -129, -256, -257, math.MinInt16, math.MinInt16 - 1,
+	math.MinInt32, math.MinInt32 - 1, int64(math.MinInt64),
+	1.5, -0.25, 1e300,
+	"", "hola", strings.Repeat("s", 31), strings.Repeat("s", 32), strings.Repeat("s", 256), strings.Repeat("s", 65536),
+	[]any{}, []any{1, "a", nil, []any{true}}, make([]any, 16), make([]any, 65536),
+	map[string]any{}, map[string]any{"b": 1, "a": map[string]any{"c": nil, "d": []any{1.5}}},
+	nestedPayload{
+		Name:     "root",
+		Tags:     []string{"x", "y"},
+		Owner:    &nestedPayload{Name: "owner", Limits: map[string]int{"max": 300, "min": -40}},
+		Children: []nestedPayload{{Name: "child", Ratio: 0.5}},
+		Enabled:  true,
+	},
+}
+
+func encodeHex(t *testing.T, encoder Encoder, value any) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, value); err != nil {
+		t.Fatalf("encoding %v: %v", value, err)
+	}
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestMsgpackVectors(t *testing.T) {
+	vectors := []binaryVector{
+		{nil, "c0"},
+		{false, "c2"},
+		{true, "c3"},
+		{0, "00"},
+		{127, "7f"},
+		{128, "d10080"},
+		{32767, "d17fff"},
+		{32768, "d200008000"},
+		{math.MaxInt32, "d27fffffff"},
+		{math.MaxInt32 + 1, "d30000000080000000"},
+		{int64(math.MaxInt64), "d37fffffffffffffff"},
+		{uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{-1, "ff"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{-128, "d080"},
+		{-129, "d1ff7f"},
+		{math.MinInt16 - 1, "d2ffff7fff"},
+		{math.MinInt32 - 1, "d3ffffffff7fffffff"},
+		{int64(math.MinInt64), "d38000000000000000"},
+		{1.5, "cb3ff8000000000000"},
+		{"a", "a161"},
+		{strings.Repeat("a", 32), "d920" + strings.Repeat("61", 32)},
+		{[]any{1, []any{nil}}, "920191c0"},
+		{make([]any, 16), "dc0010" + strings.Repeat("c0", 16)},
+		{map[string]any{"b": []any{nil}, "a": 1}, "82a16101a16291c0"},
+		{[]byte{1, 2}, "c4020102"},
+		{[]any{[]byte{1, 2}}, "91a44151493d"},
+	}
+
+	for _, vector := range vectors {
+		if got := encodeHex(t, MsgpackEncoder{}, vector.value); got != vector.expected {
+			t.Fatalf("msgpack %v: expected %s, got %s", vector.value, vector.expected, got)
+		}
+	}
+}
+
+func TestCborVectors(t *testing.T) {
+	// vectores del apéndice A de RFC 8949 (los flotantes se codifican siempre en 64 bits)
+	vectors := []binaryVector{
+		{nil, "f6"},
+		{false, "f4"},
+		{true, "f5"},
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{255, "18ff"},
+		{256, "190100"},
+		{65535, "19ffff"},
+		{65536, "1a00010000"},
+		{math.MaxUint32, "1affffffff"},
+		{math.MaxUint32 + 1, "1b0000000100000000"},
+		{uint64(math.MaxUint64), "1bffffffffffffffff"},
+		{-1, "20"},
+		{-24, "37"},
+		{-25, "3818"},
+		{-256, "38ff"},
+		{-257, "390100"},
+		{math.MinInt32, "3a7fffffff"},
+		{int64(math.MinInt64), "3b7fffffffffffffff"},
+		{1.5, "fb3ff8000000000000"},
+		{"", "60"},
+		{"IETF", "6449455446"},
+		{[]any{}, "80"},
+		{[]any{1, []any{2, 3}, []any{4, 5}}, "8301820203820405"},
+		{map[string]any{}, "a0"},
+		{map[string]any{"a": 1, "b": []any{2, 3}}, "a26161016162820203"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+	}
+
+	for _, vector := range vectors {
+		if got := encodeHex(t, CborEncoder{}, vector.value); got != vector.expected {
+			t.Fatalf("cbor %v: expected %s, got %s", vector.value, vector.expected, got)
+		}
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	decoders := map[string]struct {
+		encoder Encoder
+		decode  func(*bytes.Reader) (any, error)
+	}{
+		"msgpack": {MsgpackEncoder{}, decodeMsgpack},
+		"cbor":    {CborEncoder{}, decodeCbor},
+	}
+
+	for name, format := range decoders {
+		for _, value := range roundTripValues {
+			var buf bytes.Buffer
+			if err := format.encoder.Encode(&buf, value); err != nil {
+				t.Fatalf("%s: encoding %T: %v", name, value, err)
+			}
+
+			reader := bytes.NewReader(buf.Bytes())
+			decoded, err := format.decode(reader)
+			if err != nil {
+				t.Fatalf("%s: decoding %T: %v", name, value, err)
+			}
+
+			if reader.Len() != 0 {
+				t.Fatalf("%s: %d trailing bytes after %T", name, reader.Len(), value)
+			}
+
+			// los encoders convierten con las etiquetas json, se compara contra el valor normalizado
+			// (json.Marshal ordena las llaves de los mapas)
+			generic, _ := normalize(value)
+			expected, _ := json.Marshal(generic)
+			got, _ := json.Marshal(decoded)
+			if !bytes.Equal(expected, got) {
+				t.Fatalf("%s: expected %.200s, got %.200s", name, expected, got)
+			}
+		}
+	}
+}
+
+func readBig(reader *bytes.Reader, size int) (uint64, error) {
+	raw := make([]byte, size)
+	if _, err := reader.Read(raw); err != nil || size == 0 {
+		return 0, err
+	}
+
+	padded := make([]byte, 8)
+	copy(padded[8-size:], raw)
+	return binary.BigEndian.Uint64(padded), nil
+}
+
+func decodeMsgpack(reader *bytes.Reader) (any, error) {
+	head, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	readLength := func(size int) (int, error) {
+		length, err := readBig(reader, size)
+		return int(length), err
+	}
+
+	readString := func(length int) (string, error) {
+		raw := make([]byte, length)
+		_, err := reader.Read(raw)
+		if length == 0 {
+			err = nil
+		}
+		return string(raw), err
+	}
+
+	readArray := func(length int) (any, error) {
+		items := make([]any, length)
+		for i := range items {
+			if items[i], err = decodeMsgpack(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	readMap := func(length int) (any, error) {
+		values := map[string]any{}
+		for i := 0; i < length; i++ {
+			key, err := decodeMsgpack(reader)
+			if err != nil {
+				return nil, err
+			}
+			if values[key.(string)], err = decodeMsgpack(reader); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+
+	switch {
+	case head <= 0x7f:
+		return int64(head), nil
+	case head >= 0xe0:
+		return int64(int8(head)), nil
+	case head&0xe0 == 0xa0:
+		return readString(int(head & 0x1f))
+	case head&0xf0 == 0x90:
+		return readArray(int(head & 0x0f))
+	case head&0xf0 == 0x80:
+		return readMap(int(head & 0x0f))
+	}
+
+	switch head {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return head == 0xc3, nil
+	case 0xc4:
+		length, err := readLength(1)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := readString(length)
+		return []byte(raw), err
+	case 0xcb:
+		bits, err := readBig(reader, 8)
+		return math.Float64frombits(bits), err
+	case 0xcf:
+		return readBig(reader, 8)
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (head - 0xd0)
+		value, err := readBig(reader, size)
+		shift := 64 - 8*size
+		return int64(value<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		length, err := readLength(1 << (head - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return readString(length)
+	case 0xdc, 0xdd:
+		length, err := readLength(2 << (head - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readArray(length)
+	case 0xde, 0xdf:
+		length, err := readLength(2 << (head - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMap(length)
+	}
+
+	return nil, fmt.Errorf("unexpected msgpack byte %#x", head)
+}
+
+func decodeCbor(reader *bytes.Reader) (any, error) {
+	head, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	major, info := head>>5, head&0x1f
+	if major == 7 {
+		switch info {
+		case 20, 21:
+			return info == 21, nil
+		case 22:
+			return nil, nil
+		case 27:
+			bits, err := readBig(reader, 8)
+			return math.Float64frombits(bits), err
+		}
+		return nil, fmt.Errorf("unexpected cbor simple value %d", info)
+	}
+
+	argument := uint64(info)
+	if info >= 24 && info <= 27 {
+		if argument, err = readBig(reader, 1<<(info-24)); err != nil {
+			return nil, err
+		}
+	} else if info > 27 {
+		return nil, fmt.Errorf("unexpected cbor additional info %d", info)
+	}
+
+	switch major {
+	case 0:
+		if argument > math.MaxInt64 {
+			return argument, nil
+		}
+		return int64(argument), nil
+	case 1:
+		return -1 - int64(argument), nil
+	case 2, 3:
+		raw := make([]byte, argument)
+		if argument > 0 {
+			if _, err := reader.Read(raw); err != nil {
+				return nil, err
+			}
+		}
+		if major == 2 {
+			return raw, nil
+		}
+		return string(raw), nil
+	case 4:
+		items := make([]any, argument)
+		for i := range items {
+			if items[i], err = decodeCbor(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case 5:
+		values := map[string]any{}
+		for i := uint64(0); i < argument; i++ {
+			key, err := decodeCbor(reader)
+			if err != nil {
+				return nil, err
+			}
+			if values[key.(string)], err = decodeCbor(reader); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("unexpected cbor major type %d", major)
+}
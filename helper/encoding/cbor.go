@@ -0,0 +1,104 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+)
+
+// CborEncoder implementa CBOR (RFC 8949) sin dependencias externas, con la misma conversión
+// previa que MsgpackEncoder. Las llaves de los mapas se ordenan para que la salida sea estable
+type CborEncoder struct{}
+
+func (CborEncoder) MediaTypes() []string {
+	return []string{"application/cbor"}
+}
+
+func (CborEncoder) ContentType() string {
+	return "application/cbor"
+}
+
+func (CborEncoder) Encode(w io.Writer, data any) error {
+	buf := &bytes.Buffer{}
+
+	if raw, ok := data.([]byte); ok {
+		writeCborHead(buf, 2, uint64(len(raw)))
+		buf.Write(raw)
+	} else {
+		generic, err := normalize(data)
+		if err != nil {
+			return err
+		}
+
+		writeCbor(buf, generic)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeCbor(buf *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			if i >= 0 {
+				writeCborHead(buf, 0, uint64(i))
+			} else {
+				writeCborHead(buf, 1, uint64(-(i + 1)))
+			}
+		} else if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			writeCborHead(buf, 0, u)
+		} else {
+			f, _ := v.Float64()
+			buf.WriteByte(0xfb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		}
+	case string:
+		writeCborHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []any:
+		writeCborHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			writeCbor(buf, item)
+		}
+	case map[string]any:
+		writeCborHead(buf, 5, uint64(len(v)))
+		for _, key := range sortedKeys(v) {
+			writeCbor(buf, key)
+			writeCbor(buf, v[key])
+		}
+	}
+}
+
+// writeCborHead escribe el tipo mayor y el argumento con la codificación más corta
+func writeCborHead(buf *bytes.Buffer, major byte, argument uint64) {
+	major <<= 5
+
+	switch {
+	case argument < 24:
+		buf.WriteByte(major | byte(argument))
+	case argument <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(argument))
+	case argument <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(argument))
+	case argument <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(argument))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, argument)
+	}
+}
@@ -0,0 +1,108 @@
+package encoding
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// CsvEncoder solo acepta slices, los elementos que son objetos generan una columna por llave
+// (ordenadas alfabéticamente) y los valores anidados se escriben como JSON
+type CsvEncoder struct{}
+
+func (CsvEncoder) MediaTypes() []string {
+	return []string{"text/csv"}
+}
+
+func (CsvEncoder) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (CsvEncoder) Encode(w io.Writer, data any) error {
+	writer := csv.NewWriter(w)
+
+	if rows, ok := data.([][]string); ok {
+		if err := writer.WriteAll(rows); err != nil {
+			return err
+		}
+		return writer.Error()
+	}
+
+	generic, err := normalize(data)
+	if err != nil {
+		return err
+	}
+
+	items, ok := generic.([]any)
+	if !ok {
+		return ErrUnsupported
+	}
+
+	seen := map[string]any{}
+	objects := true
+	for _, item := range items {
+		values, isObject := item.(map[string]any)
+		if !isObject {
+			objects = false
+			break
+		}
+
+		for key := range values {
+			seen[key] = nil
+		}
+	}
+	columns := sortedKeys(seen)
+
+	if !objects || len(items) == 0 {
+		if err := writer.Write([]string{"value"}); err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if err := writer.Write([]string{csvValue(item)}); err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+		return writer.Error()
+	}
+
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		values := item.(map[string]any)
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = csvValue(values[column])
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		content, _ := json.Marshal(v)
+		return string(content)
+	}
+}
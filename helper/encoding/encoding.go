@@ -0,0 +1,190 @@
+package encoding
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupported indica que el encoder no puede representar el valor recibido (ej. CSV con un
+// valor que no es un slice), la negociación continúa con el siguiente tipo aceptado
+var ErrUnsupported = errors.New("value not supported by encoder")
+
+// Encoder serializa la respuesta en un formato negociable por el header Accept
+type Encoder interface {
+	// MediaTypes son los tipos que se comparan contra Accept (ej. "application/msgpack")
+	MediaTypes() []string
+	// ContentType es el valor enviado en el header Content-Type
+	ContentType() string
+	Encode(w io.Writer, data any) error
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = []Encoder{JsonEncoder{}, XmlEncoder{}, TextEncoder{}, CsvEncoder{}, MsgpackEncoder{}, CborEncoder{}}
+)
+
+// Register agrega un encoder o reemplaza al registrado con el mismo tipo principal, el primer
+// encoder registrado (JSON) se usa cuando el cliente no envía Accept
+func Register(encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	for i, current := range encoders {
+		if current.MediaTypes()[0] == encoder.MediaTypes()[0] {
+			encoders[i] = encoder
+			return
+		}
+	}
+
+	encoders = append(encoders, encoder)
+}
+
+// Lookup obtiene el encoder registrado para un Content-Type
+func Lookup(contentType string) Encoder {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	for _, encoder := range encoders {
+		for _, candidate := range encoder.MediaTypes() {
+			if candidate == mediaType {
+				return encoder
+			}
+		}
+	}
+
+	return nil
+}
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// Negotiate retorna los encoders aceptados por el header Accept ordenados por calidad (q),
+// con el mismo q se respeta el orden de registro. Sin Accept se retornan todos
+func Negotiate(accept string) []Encoder {
+	encodersMu.RLock()
+	registered := append([]Encoder{}, encoders...)
+	encodersMu.RUnlock()
+
+	if strings.TrimSpace(accept) == "" {
+		return registered
+	}
+
+	ranges := parseAccept(accept)
+
+	type candidate struct {
+		encoder Encoder
+		quality float64
+	}
+
+	candidates := []candidate{}
+	for _, encoder := range registered {
+		if quality := acceptQuality(ranges, encoder); quality > 0 {
+			candidates = append(candidates, candidate{encoder: encoder, quality: quality})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	result := make([]Encoder, 0, len(candidates))
+	for _, c := range candidates {
+		result = append(result, c.encoder)
+	}
+
+	return result
+}
+
+func parseAccept(accept string) []acceptRange {
+	ranges := []acceptRange{}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.TrimSpace(key) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+
+	return ranges
+}
+
+// acceptQuality obtiene la calidad del rango más específico que coincide con el encoder
+// (tipo exacto > "tipo/*" > "*/*"), así "*/*;q=0.1, application/json" prefiere JSON
+func acceptQuality(ranges []acceptRange, encoder Encoder) float64 {
+	best := 0.0
+	bestSpecificity := -1
+
+	for _, mediaType := range encoder.MediaTypes() {
+		mainType, _, _ := strings.Cut(mediaType, "/")
+
+		for _, r := range ranges {
+			specificity := -1
+			switch r.mediaType {
+			case mediaType:
+				specificity = 2
+			case mainType + "/*":
+				specificity = 1
+			case "*/*":
+				specificity = 0
+			}
+
+			if specificity > bestSpecificity || (specificity == bestSpecificity && specificity >= 0 && r.quality > best) {
+				best = r.quality
+				bestSpecificity = specificity
+			}
+		}
+	}
+
+	return best
+}
+
+// normalize convierte structs y tipos propios a valores genéricos (map[string]any, []any,
+// json.Number, string, bool, nil) usando las etiquetas json, para los encoders sin reflexión propia
+func normalize(data any) (any, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func sortedKeys(values map[string]any) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
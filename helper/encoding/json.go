@@ -0,0 +1,32 @@
+package encoding
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type JsonEncoder struct{}
+
+func (JsonEncoder) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+func (JsonEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (JsonEncoder) Encode(w io.Writer, data any) error {
+	// mismo comportamiento que goroutes.JsonResponse con los tipos que se serializan solos
+	if v, ok := data.(interface{ ToJson() []byte }); ok {
+		_, err := w.Write(v.ToJson())
+		return err
+	}
+
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(content)
+	return err
+}
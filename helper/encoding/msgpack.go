@@ -0,0 +1,149 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+)
+
+// MsgpackEncoder implementa MessagePack sin dependencias externas. Los valores se convierten
+// primero con las etiquetas json (ver normalize), por lo que los []byte anidados quedan en base64;
+// un []byte en la raíz se envía como bin
+type MsgpackEncoder struct{}
+
+func (MsgpackEncoder) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+func (MsgpackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+func (MsgpackEncoder) Encode(w io.Writer, data any) error {
+	buf := &bytes.Buffer{}
+
+	if raw, ok := data.([]byte); ok {
+		writeMsgpackBytes(buf, raw)
+	} else {
+		generic, err := normalize(data)
+		if err != nil {
+			return err
+		}
+
+		writeMsgpack(buf, generic)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeMsgpack(buf *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			writeMsgpackInt(buf, i)
+		} else if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, u)
+		} else {
+			f, _ := v.Float64()
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		}
+	case string:
+		length := len(v)
+		switch {
+		case length < 32:
+			buf.WriteByte(0xa0 | byte(length))
+		case length <= math.MaxUint8:
+			buf.WriteByte(0xd9)
+			buf.WriteByte(byte(length))
+		case length <= math.MaxUint16:
+			buf.WriteByte(0xda)
+			binary.Write(buf, binary.BigEndian, uint16(length))
+		default:
+			buf.WriteByte(0xdb)
+			binary.Write(buf, binary.BigEndian, uint32(length))
+		}
+		buf.WriteString(v)
+	case []any:
+		length := len(v)
+		switch {
+		case length < 16:
+			buf.WriteByte(0x90 | byte(length))
+		case length <= math.MaxUint16:
+			buf.WriteByte(0xdc)
+			binary.Write(buf, binary.BigEndian, uint16(length))
+		default:
+			buf.WriteByte(0xdd)
+			binary.Write(buf, binary.BigEndian, uint32(length))
+		}
+		for _, item := range v {
+			writeMsgpack(buf, item)
+		}
+	case map[string]any:
+		length := len(v)
+		switch {
+		case length < 16:
+			buf.WriteByte(0x80 | byte(length))
+		case length <= math.MaxUint16:
+			buf.WriteByte(0xde)
+			binary.Write(buf, binary.BigEndian, uint16(length))
+		default:
+			buf.WriteByte(0xdf)
+			binary.Write(buf, binary.BigEndian, uint32(length))
+		}
+		for _, key := range sortedKeys(v) {
+			writeMsgpack(buf, key)
+			writeMsgpack(buf, v[key])
+		}
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 127:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+func writeMsgpackBytes(buf *bytes.Buffer, raw []byte) {
+	length := len(raw)
+	switch {
+	case length <= math.MaxUint8:
+		buf.WriteByte(0xc4)
+		buf.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(0xc5)
+		binary.Write(buf, binary.BigEndian, uint16(length))
+	default:
+		buf.WriteByte(0xc6)
+		binary.Write(buf, binary.BigEndian, uint32(length))
+	}
+	buf.Write(raw)
+}
@@ -0,0 +1,40 @@
+package encoding
+
+import (
+	"fmt"
+	"io"
+)
+
+// TextEncoder solo acepta textos, errores y valores escalares, los mapas y structs no tienen
+// una representación en texto plano
+type TextEncoder struct{}
+
+func (TextEncoder) MediaTypes() []string {
+	return []string{"text/plain"}
+}
+
+func (TextEncoder) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (TextEncoder) Encode(w io.Writer, data any) error {
+	var text string
+
+	switch v := data.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case error:
+		text = v.Error()
+	case fmt.Stringer:
+		text = v.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		text = fmt.Sprint(v)
+	default:
+		return ErrUnsupported
+	}
+
+	_, err := io.WriteString(w, text)
+	return err
+}
@@ -0,0 +1,137 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type xmlPayload struct {
+	XMLName xml.Name `xml:"user"`
+	ID      int      `xml:"id,attr"`
+	Name    string   `xml:"name"`
+	Tags    []string `xml:"tags>tag"`
+	Manager *struct {
+		Name string `xml:"name"`
+	} `xml:"manager"`
+}
+
+func TestXmlGeneric(t *testing.T) {
+	data := map[string]any{
+		"name":    "a<b",
+		"count":   -129,
+		"ratio":   1.5,
+		"active":  true,
+		"missing": nil,
+		"1st":     "x",
+		"with sp": "y",
+		"items":   []any{1, nil, map[string]any{"id": 2}},
+		"nested":  map[string]any{"empty": map[string]any{}},
+	}
+
+	var buf bytes.Buffer
+	if err := (XmlEncoder{}).Encode(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := xml.Header + "<response><_1st>x</_1st><active>true</active><count>-129</count>" +
+		"<items><item>1</item><item></item><item><id>2</id></item></items><missing></missing>" +
+		"<name>a&lt;b</name><nested><empty></empty></nested><ratio>1.5</ratio><with_sp>y</with_sp></response>"
+	if buf.String() != expected {
+		t.Fatalf("expected %s, got %s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := (XmlEncoder{}).Encode(&buf, nil); err != nil || buf.String() != xml.Header+"<response></response>" {
+		t.Fatalf("expected an empty response for nil, got %q (%v)", buf.String(), err)
+	}
+}
+
+func TestXmlStructRoundTrip(t *testing.T) {
+	original := xmlPayload{ID: 7, Name: "root & co", Tags: []string{"x", "y"}}
+	original.Manager = &struct {
+		Name string `xml:"name"`
+	}{Name: "boss"}
+
+	for _, value := range []any{original, &original} {
+		var buf bytes.Buffer
+		if err := (XmlEncoder{}).Encode(&buf, value); err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded xmlPayload
+		if err := xml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.ID != 7 || decoded.Name != original.Name || strings.Join(decoded.Tags, ",") != "x,y" || decoded.Manager == nil || decoded.Manager.Name != "boss" {
+			t.Fatalf("expected the struct after decoding, got %+v", decoded)
+		}
+	}
+}
+
+func TestCsvObjects(t *testing.T) {
+	data := []map[string]any{
+		{"name": "a,b", "count": 128, "nested": map[string]any{"x": []any{1, nil}}},
+		{"active": true, "name": "line\nbreak", "missing": nil},
+	}
+
+	var buf bytes.Buffer
+	if err := (CsvEncoder{}).Encode(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"active", "count", "missing", "name", "nested"},
+		{"", "128", "", "a,b", `{"x":[1,null]}`},
+		{"true", "", "", "line\nbreak", ""},
+	}
+
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d: %q", len(expected), len(records), records)
+	}
+
+	for i := range expected {
+		if strings.Join(records[i], "|") != strings.Join(expected[i], "|") {
+			t.Fatalf("record %d: expected %q, got %q", i, expected[i], records[i])
+		}
+	}
+}
+
+func TestCsvValues(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     any
+		expected string
+	}{
+		{"scalars", []any{1, "a", nil, false, []any{2}}, "value\n1\na\n\nfalse\n[2]\n"},
+		{"mixed", []any{map[string]any{"a": 1}, 2}, "value\n\"{\"\"a\"\":1}\"\n2\n"},
+		{"empty", []any{}, "value\n"},
+		{"rows", [][]string{{"a", "b"}, {"1", "2"}}, "a,b\n1,2\n"},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := (CsvEncoder{}).Encode(&buf, c.data); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		if buf.String() != c.expected {
+			t.Fatalf("%s: expected %q, got %q", c.name, c.expected, buf.String())
+		}
+	}
+
+	for _, data := range []any{map[string]any{"a": 1}, "text", nil} {
+		if err := (CsvEncoder{}).Encode(&bytes.Buffer{}, data); !errors.Is(err, ErrUnsupported) {
+			t.Fatalf("expected ErrUnsupported for %v, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package encoding
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// XmlEncoder usa encoding/xml para structs (respetando las etiquetas xml) y para mapas y slices
+// genera un documento con raíz <response>, los elementos de los slices se llaman <item>
+type XmlEncoder struct{}
+
+func (XmlEncoder) MediaTypes() []string {
+	return []string{"application/xml", "text/xml"}
+}
+
+func (XmlEncoder) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (XmlEncoder) Encode(w io.Writer, data any) error {
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if value.Kind() == reflect.Struct {
+		if content, err := xml.Marshal(data); err == nil {
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return err
+			}
+
+			_, err = w.Write(content)
+			return err
+		}
+	}
+
+	generic, err := normalize(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	if err := writeXmlElement(encoder, "response", generic); err != nil {
+		return err
+	}
+
+	return encoder.Flush()
+}
+
+func writeXmlElement(encoder *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case nil:
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			if err := writeXmlElement(encoder, key, v[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := writeXmlElement(encoder, "item", item); err != nil {
+				return err
+			}
+		}
+	case json.Number:
+		if err := encoder.EncodeToken(xml.CharData(v.String())); err != nil {
+			return err
+		}
+	case bool:
+		text := "false"
+		if v {
+			text = "true"
+		}
+		if err := encoder.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	case string:
+		if err := encoder.EncodeToken(xml.CharData(v)); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// xmlName convierte la llave del mapa en un nombre de elemento válido
+func xmlName(name string) string {
+	var builder strings.Builder
+
+	for i, char := range name {
+		valid := unicode.IsLetter(char) || char == '_' || (i > 0 && (unicode.IsDigit(char) || char == '-' || char == '.'))
+		if !valid {
+			if i == 0 && unicode.IsDigit(char) {
+				builder.WriteRune('_')
+				builder.WriteRune(char)
+				continue
+			}
+			char = '_'
+		}
+		builder.WriteRune(char)
+	}
+
+	if builder.Len() == 0 {
+		return "_"
+	}
+
+	return builder.String()
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/helper/encoding"
 )
 
 func Response(res http.ResponseWriter, body any, statusCode int, contentType string) {
@@ -33,8 +34,23 @@ func Response(res http.ResponseWriter, body any, statusCode int, contentType str
 
 	if contentType == "application/json" {
 		json.NewEncoder(res).Encode(body)
-	} else {
-		res.Write([]byte(body.(string)))
+		return
+	}
+
+	switch value := body.(type) {
+	case string:
+		res.Write([]byte(value))
+	case []byte:
+		res.Write(value)
+	default:
+		// cualquier otro valor se serializa con el encoder del Content-Type o como JSON
+		if encoder := encoding.Lookup(contentType); encoder != nil {
+			if err := encoder.Encode(res, body); !errors.Is(err, encoding.ErrUnsupported) {
+				return
+			}
+		}
+
+		json.NewEncoder(res).Encode(body)
 	}
 }
 
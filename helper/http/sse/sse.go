@@ -0,0 +1,262 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nemutagk/goenvars"
+)
+
+// Event es un mensaje de Server-Sent Events. Data puede ser string, []byte o cualquier valor
+// serializable a JSON; los saltos de línea se envían como varias líneas "data:"
+type Event struct {
+	ID    string
+	Event string
+	Data  any
+	// Retry indica al navegador cuánto esperar antes de reconectarse
+	Retry time.Duration
+}
+
+// StreamConfig define el comportamiento del stream
+type StreamConfig struct {
+	// HeartbeatInterval es cada cuánto se envía un comentario para mantener viva la conexión
+	// a través de proxies, 0 lo desactiva
+	HeartbeatInterval time.Duration
+	// Retry se envía al abrir el stream, 0 deja el valor por defecto del navegador
+	Retry time.Duration
+}
+
+// LoadStreamConfigFromEnv lee GOROUTES_SSE_HEARTBEAT y GOROUTES_SSE_RETRY (segundos)
+func LoadStreamConfigFromEnv() StreamConfig {
+	return StreamConfig{
+		HeartbeatInterval: time.Duration(goenvars.GetEnvInt("GOROUTES_SSE_HEARTBEAT", 15)) * time.Second,
+		Retry:             time.Duration(goenvars.GetEnvInt("GOROUTES_SSE_RETRY", 0)) * time.Second,
+	}
+}
+
+// Stream envía eventos al cliente. Las escrituras son seguras entre goroutines y fallan con el
+// error del contexto cuando el cliente se desconecta.
+//
+// Las rutas con streams deben usar un Timeout negativo si el grupo define uno (TimeoutMiddleware
+// guarda la respuesta en memoria)
+type Stream struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	ctx         context.Context
+	cancel      context.CancelFunc
+	lastEventId string
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewStream envía los headers de SSE y empieza el heartbeat. El handler debe llamar Close antes
+// de terminar (normalmente con defer). Retorna error si el writer no soporta Flush.
+func NewStream(w http.ResponseWriter, r *http.Request, config StreamConfig) (*Stream, error) {
+	rc := http.NewResponseController(w)
+
+	// el stream dura lo que el cliente siga conectado, se quita el WriteTimeout del servidor
+	rc.SetWriteDeadline(time.Time{})
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	if r.ProtoMajor == 1 {
+		header.Set("Connection", "keep-alive")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	s := &Stream{
+		w:           w,
+		rc:          rc,
+		ctx:         ctx,
+		cancel:      cancel,
+		lastEventId: r.Header.Get("Last-Event-ID"),
+	}
+
+	// EventSource no permite headers propios, al reconectar manualmente se acepta el query param
+	if s.lastEventId == "" {
+		s.lastEventId = r.URL.Query().Get("lastEventId")
+	}
+
+	if config.Retry > 0 {
+		if err := s.write("retry: " + strconv.FormatInt(config.Retry.Milliseconds(), 10) + "\n\n"); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	if config.HeartbeatInterval > 0 {
+		s.wg.Add(1)
+		go s.heartbeat(config.HeartbeatInterval)
+	}
+
+	return s, nil
+}
+
+// LastEventID es el ID del último evento recibido por el cliente antes de reconectarse, permite
+// reanudar el stream desde ese punto
+func (s *Stream) LastEventID() string {
+	return s.lastEventId
+}
+
+// Context se cancela cuando el cliente se desconecta o se cierra el stream
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+// Done se cierra cuando el cliente se desconecta o se cierra el stream
+func (s *Stream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// ErrInvalidField indica un id o tipo de evento con saltos de línea (o NUL en el id), enviarlo
+// permitiría agregar campos al evento
+var ErrInvalidField = errors.New("sse: event id and type cannot contain line breaks")
+
+// Send envía el evento, retorna ErrInvalidField si el id o el tipo tienen saltos de línea
+func (s *Stream) Send(event Event) error {
+	if strings.ContainsAny(event.ID, "\r\n\x00") || strings.ContainsAny(event.Event, "\r\n") {
+		return ErrInvalidField
+	}
+
+	var builder strings.Builder
+
+	if event.ID != "" {
+		builder.WriteString("id: " + event.ID + "\n")
+	}
+
+	if event.Event != "" {
+		builder.WriteString("event: " + event.Event + "\n")
+	}
+
+	if event.Retry > 0 {
+		builder.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	data, err := eventData(event.Data)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range splitLines(data) {
+		builder.WriteString("data: " + line + "\n")
+	}
+	builder.WriteString("\n")
+
+	return s.write(builder.String())
+}
+
+// Comment envía un comentario, los clientes lo ignoran
+func (s *Stream) Comment(text string) error {
+	var builder strings.Builder
+	for _, line := range splitLines(text) {
+		builder.WriteString(": " + line + "\n")
+	}
+	builder.WriteString("\n")
+
+	return s.write(builder.String())
+}
+
+// Close detiene el heartbeat, después de Close el handler puede terminar
+func (s *Stream) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Stream) write(content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	if s.closed {
+		return errors.New("sse stream closed")
+	}
+
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.w.Write([]byte(content)); err != nil {
+		s.fail(err)
+		return err
+	}
+
+	if err := s.rc.Flush(); err != nil {
+		s.fail(err)
+		return err
+	}
+
+	return nil
+}
+
+// fail guarda el error de escritura (cliente desconectado) y cancela el contexto del stream
+func (s *Stream) fail(err error) {
+	s.err = err
+	s.cancel()
+}
+
+func (s *Stream) heartbeat(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func eventData(data any) (string, error) {
+	switch v := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+}
+
+// lineBreaks normaliza los fines de línea de SSE ("\r\n", "\r" y "\n") a "\n"
+var lineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// splitLines separa el texto con los mismos fines de línea que el cliente, un "\r" solo también
+// termina la línea y sin normalizarlo el texto podría agregar campos al evento
+func splitLines(text string) []string {
+	return strings.Split(lineBreaks.Replace(text), "\n")
+}
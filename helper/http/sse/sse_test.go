@@ -0,0 +1,86 @@
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestStream(t *testing.T) (*Stream, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	stream, err := NewStream(rec, httptest.NewRequest(http.MethodGet, "/events", nil), StreamConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stream.Close)
+
+	return stream, rec
+}
+
+func TestSendEvent(t *testing.T) {
+	stream, rec := newTestStream(t)
+
+	if err := stream.Send(Event{ID: "7", Event: "update", Data: map[string]int{"count": 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "id: 7\nevent: update\ndata: {\"count\":1}\n\n"
+	if rec.Body.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, rec.Body.String())
+	}
+
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestSendLineBreaks(t *testing.T) {
+	stream, rec := newTestStream(t)
+
+	if err := stream.Send(Event{Data: "a\r\nb\rc\nd"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.Send(Event{Data: "x\revent: admin\rid: 9"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.Comment("one\rtwo"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "data: a\ndata: b\ndata: c\ndata: d\n\n" +
+		"data: x\ndata: event: admin\ndata: id: 9\n\n" +
+		": one\n: two\n\n"
+	if rec.Body.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, rec.Body.String())
+	}
+
+	if strings.Contains(rec.Body.String(), "\r") {
+		t.Fatal("the stream must not contain carriage returns")
+	}
+}
+
+func TestSendInvalidField(t *testing.T) {
+	stream, rec := newTestStream(t)
+
+	for _, event := range []Event{
+		{ID: "1\nevent: admin"},
+		{ID: "1\r"},
+		{ID: "1\x00"},
+		{Event: "update\rid: 9"},
+		{Event: "update\n"},
+	} {
+		if err := stream.Send(event); !errors.Is(err, ErrInvalidField) {
+			t.Fatalf("%+v: expected ErrInvalidField, got %v", event, err)
+		}
+	}
+
+	if rec.Body.Len() != 0 {
+		t.Fatalf("invalid events must not be written, got %q", rec.Body.String())
+	}
+}
@@ -1,55 +1,434 @@
 package wr
 
 import (
-	"bytes"
+	"bufio"
+	"errors"
+	"io"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 )
 
-type responseWriterRecorder struct {
+// ResponseWriter envuelve el http.ResponseWriter de la petición para registrar el status, los
+// bytes enviados y los tiempos de la respuesta sin guardar el body en memoria (opcionalmente
+// guarda los primeros bytes con CaptureBody). Implementa Flusher y Unwrap, por lo que
+// http.ResponseController llega al writer original; Writer agrega Hijacker, Pusher e
+// io.ReaderFrom solo si el writer original los implementa
+type ResponseWriter struct {
+	mu          sync.Mutex
 	rw          http.ResponseWriter
+	released    bool
+	streamed    bool
 	status      int
 	wroteHeader bool
-	body        bytes.Buffer
+	bytes       int
+	start       time.Time
+	firstByte   time.Time
+	end         time.Time
+	captureMax  int
+	captured    []byte
 }
 
-func NewResponseRecorder(rw http.ResponseWriter) *responseWriterRecorder {
-	return &responseWriterRecorder{rw: rw, status: http.StatusOK}
+// ErrReleased es el error de las escrituras después de Release (ej. una goroutine que sigue
+// escribiendo cuando el handler ya terminó)
+var ErrReleased = errors.New("wr: response writer used after Release")
+
+var pool = sync.Pool{
+	New: func() any {
+		return &ResponseWriter{}
+	},
 }
 
-func (r *responseWriterRecorder) Header() http.Header {
-	return r.rw.Header()
+// Acquire obtiene un ResponseWriter del pool, debe regresarse con Release cuando el handler termina
+func Acquire(rw http.ResponseWriter) *ResponseWriter {
+	w := pool.Get().(*ResponseWriter)
+	w.reset(rw)
+
+	return w
 }
 
-func (r *responseWriterRecorder) WriteHeader(code int) {
-	if r.wroteHeader {
+// Release regresa el ResponseWriter al pool, las escrituras posteriores retornan ErrReleased.
+// Los writers con Flush o Hijack no regresan al pool porque una goroutine del stream (ej. el
+// heartbeat de SSE) puede seguir usándolos y escribiría en la respuesta de otra petición
+func Release(w *ResponseWriter) {
+	if w == nil {
 		return
 	}
-	r.status = code
-	r.wroteHeader = true
-	r.rw.WriteHeader(code)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+
+	w.released = true
+	w.rw = nil
+
+	if w.streamed {
+		return
+	}
+
+	// los buffers grandes no se guardan en el pool
+	if cap(w.captured) > 64*1024 {
+		w.captured = nil
+	}
+
+	pool.Put(w)
+}
+
+// NewResponseRecorder crea un ResponseWriter fuera del pool
+func NewResponseRecorder(rw http.ResponseWriter) *ResponseWriter {
+	w := &ResponseWriter{}
+	w.reset(rw)
+
+	return w
+}
+
+func (w *ResponseWriter) reset(rw http.ResponseWriter) {
+	w.rw = rw
+	w.released = false
+	w.streamed = false
+	w.status = http.StatusOK
+	w.wroteHeader = false
+	w.bytes = 0
+	w.start = time.Now()
+	w.firstByte = time.Time{}
+	w.end = time.Time{}
+	w.captureMax = 0
+	w.captured = w.captured[:0]
+}
+
+// CaptureBody guarda hasta limit bytes del inicio del body (ej. para logs de errores)
+func (w *ResponseWriter) CaptureBody(limit int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.captureMax = limit
+}
+
+// Writer retorna el ResponseWriter con las interfaces opcionales (Hijacker, Pusher,
+// io.ReaderFrom) que implementa el writer original, es el que se pasa al siguiente handler
+func (w *ResponseWriter) Writer() http.ResponseWriter {
+	_, isPusher := w.rw.(http.Pusher)
+	_, isReaderFrom := w.rw.(io.ReaderFrom)
+	isHijacker := canHijack(w.rw)
+
+	switch {
+	case isHijacker && isPusher && isReaderFrom:
+		return hijackPushReadFromWriter{w}
+	case isHijacker && isPusher:
+		return hijackPushWriter{w}
+	case isHijacker && isReaderFrom:
+		return hijackReadFromWriter{w}
+	case isPusher && isReaderFrom:
+		return pushReadFromWriter{w}
+	case isHijacker:
+		return hijackWriter{w}
+	case isPusher:
+		return pushWriter{w}
+	case isReaderFrom:
+		return readFromWriter{w}
+	}
+
+	return w
 }
 
-func (r *responseWriterRecorder) Write(b []byte) (int, error) {
-	// forward to underlying writer and record bytes
-	n, err := r.rw.Write(b)
-	if n > 0 {
-		r.body.Write(b[:n])
+func (w *ResponseWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return http.Header{}
 	}
-	// if Write was used without WriteHeader, ensure status is set
-	if !r.wroteHeader {
-		r.wroteHeader = true
-		r.status = http.StatusOK
+
+	return w.rw.Header()
+}
+
+func (w *ResponseWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.wroteHeader || w.released {
+		return
+	}
+
+	// las respuestas informativas (ej. 103 Early Hints) no son el status final
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.rw.WriteHeader(code)
+		return
 	}
+
+	w.status = code
+	w.wroteHeader = true
+	w.markFirstByte()
+	w.rw.WriteHeader(code)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return 0, ErrReleased
+	}
+
+	w.beforeWrite()
+
+	n, err := w.rw.Write(b)
+	w.bytes += n
+	w.capture(b[:n])
+
 	return n, err
 }
 
-// optional: forward Flush if underlying writer supports it
-func (r *responseWriterRecorder) Flush() {
-	if f, ok := r.rw.(http.Flusher); ok {
-		f.Flush()
+// readFrom usa el io.ReaderFrom del writer original (ej. sendfile) cuando no se captura el body
+func (w *ResponseWriter) readFrom(src io.Reader) (int64, error) {
+	w.mu.Lock()
+
+	if w.released {
+		w.mu.Unlock()
+		return 0, ErrReleased
+	}
+
+	if rf, ok := w.rw.(io.ReaderFrom); ok && w.captureMax == 0 {
+		defer w.mu.Unlock()
+
+		w.beforeWrite()
+		n, err := rf.ReadFrom(src)
+		w.bytes += int(n)
+		return n, err
+	}
+
+	w.mu.Unlock()
+
+	return io.Copy(writerOnly{w}, src)
+}
+
+// Flush envía al cliente lo escrito, atraviesa otros wrappers que implementen Unwrap
+func (w *ResponseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError es el método que usa http.ResponseController, retorna el error del writer original
+func (w *ResponseWriter) FlushError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return ErrReleased
+	}
+
+	// el flush envía los headers con el status actual
+	w.streamed = true
+	w.wroteHeader = true
+	w.markFirstByte()
+
+	return http.NewResponseController(w.rw).Flush()
+}
+
+// hijack entrega la conexión (ej. WebSocket), la respuesta se registra como 101 Switching Protocols
+func (w *ResponseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return nil, nil, ErrReleased
+	}
+
+	conn, brw, err := http.NewResponseController(w.rw).Hijack()
+	if err == nil {
+		w.streamed = true
+		w.wroteHeader = true
+		w.status = http.StatusSwitchingProtocols
+		w.markFirstByte()
+	}
+
+	return conn, brw, err
+}
+
+// push inicia un server push de HTTP/2 si el writer original lo soporta
+func (w *ResponseWriter) push(target string, opts *http.PushOptions) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return ErrReleased
+	}
+
+	if pusher, ok := w.rw.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Unwrap permite a http.ResponseController llegar al writer original, nil después de Release
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.rw
+}
+
+// Status es el status enviado (200 si el handler no lo definió)
+func (w *ResponseWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.status
+}
+
+func (w *ResponseWriter) GetStatus() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.status
+}
+
+// BytesWritten retorna el número de bytes del body enviados al cliente
+func (w *ResponseWriter) BytesWritten() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.bytes
+}
+
+// WroteHeader indica si los headers ya fueron enviados al cliente
+func (w *ResponseWriter) WroteHeader() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.wroteHeader
+}
+
+// TimeToFirstByte es el tiempo entre la creación del writer y el envío de los headers,
+// 0 si todavía no se envían
+func (w *ResponseWriter) TimeToFirstByte() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.firstByte.IsZero() {
+		return 0
+	}
+
+	return w.firstByte.Sub(w.start)
+}
+
+// Duration es el tiempo desde la creación del writer, al llamar Finish queda fijo
+func (w *ResponseWriter) Duration() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.end.IsZero() {
+		return w.end.Sub(w.start)
+	}
+
+	return time.Since(w.start)
+}
+
+// Finish marca el fin de la respuesta para Duration
+func (w *ResponseWriter) Finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.end.IsZero() {
+		w.end = time.Now()
+	}
+}
+
+// Body retorna los bytes capturados con CaptureBody
+func (w *ResponseWriter) Body() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.captured
+}
+
+func (w *ResponseWriter) beforeWrite() {
+	// if Write was used without WriteHeader, ensure status is set
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = http.StatusOK
+	}
+	w.markFirstByte()
+}
+
+func (w *ResponseWriter) markFirstByte() {
+	if w.firstByte.IsZero() {
+		w.firstByte = time.Now()
+	}
+}
+
+func (w *ResponseWriter) capture(b []byte) {
+	if remaining := w.captureMax - len(w.captured); remaining > 0 {
+		if len(b) > remaining {
+			b = b[:remaining]
+		}
+		w.captured = append(w.captured, b...)
+	}
+}
+
+// writerOnly oculta ReadFrom para que io.Copy no vuelva a llamarlo
+type writerOnly struct {
+	io.Writer
+}
+
+// canHijack recorre los writers con Unwrap igual que http.ResponseController
+func canHijack(rw http.ResponseWriter) bool {
+	for rw != nil {
+		switch t := rw.(type) {
+		case http.Hijacker:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = t.Unwrap()
+		default:
+			return false
+		}
 	}
+
+	return false
 }
 
-func (r *responseWriterRecorder) GetStatus() int {
-	return r.status
+// las combinaciones de interfaces opcionales que retorna Writer, cada una expone solo lo que el
+// writer original implementa para que las validaciones de tipo (w.(http.Hijacker)) sean correctas
+
+type hijackWriter struct{ *ResponseWriter }
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type pushWriter struct{ *ResponseWriter }
+
+func (w pushWriter) Push(target string, opts *http.PushOptions) error { return w.push(target, opts) }
+
+type readFromWriter struct{ *ResponseWriter }
+
+func (w readFromWriter) ReadFrom(src io.Reader) (int64, error) { return w.readFrom(src) }
+
+type hijackPushWriter struct{ *ResponseWriter }
+
+func (w hijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+func (w hijackPushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type hijackReadFromWriter struct{ *ResponseWriter }
+
+func (w hijackReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+func (w hijackReadFromWriter) ReadFrom(src io.Reader) (int64, error)        { return w.readFrom(src) }
+
+type pushReadFromWriter struct{ *ResponseWriter }
+
+func (w pushReadFromWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+func (w pushReadFromWriter) ReadFrom(src io.Reader) (int64, error) { return w.readFrom(src) }
+
+type hijackPushReadFromWriter struct{ *ResponseWriter }
+
+func (w hijackPushReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+func (w hijackPushReadFromWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
 }
+func (w hijackPushReadFromWriter) ReadFrom(src io.Reader) (int64, error) { return w.readFrom(src) }
@@ -0,0 +1,173 @@
+package goroutes
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// hostRouters guarda por mux los hosts con parámetros ({tenant}.example.com), ServeMux solo
+// soporta hosts exactos
+var hostRouters sync.Map
+
+type hostRouter struct {
+	mu    sync.RWMutex
+	hosts []*hostMux
+}
+
+type hostMux struct {
+	host   string
+	labels []string
+	mux    *http.ServeMux
+}
+
+func getHostRouter(server *http.ServeMux) *hostRouter {
+	router, _ := hostRouters.LoadOrStore(server, &hostRouter{})
+	return router.(*hostRouter)
+}
+
+// withHost asigna el host del grupo a las rutas que no definieron uno en un subgrupo, la llave
+// queda como el patrón de ServeMux con host (ej. "api.example.com/users")
+func withHost(routes map[string]definitions.Route, host string) map[string]definitions.Route {
+	if host == "" {
+		return routes
+	}
+
+	host = strings.ToLower(host)
+
+	hostRoutes := make(map[string]definitions.Route, len(routes))
+	for path, route := range routes {
+		if route.Host != "" {
+			hostRoutes[path] = route
+			continue
+		}
+
+		route.Host = host
+		for method, subRoute := range route.Group {
+			subRoute.Host = host
+			route.Group[method] = subRoute
+		}
+
+		for version, versionRoute := range route.Versions {
+			versionRoute.Host = host
+			route.Versions[version] = versionRoute
+		}
+
+		hostRoutes[host+path] = route
+	}
+
+	return hostRoutes
+}
+
+// isWildcardHost indica si el host tiene parámetros y no puede registrarse directo en ServeMux
+func isWildcardHost(host string) bool {
+	return strings.Contains(host, "{")
+}
+
+// handle registra el patrón en el mux del host con parámetros
+func (h *hostRouter) handle(host string, pattern string, handler http.HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, hm := range h.hosts {
+		if hm.host == host {
+			hm.mux.HandleFunc(pattern, handler)
+			return
+		}
+	}
+
+	hm := &hostMux{host: host, labels: strings.Split(host, "."), mux: http.NewServeMux()}
+	hm.mux.HandleFunc(pattern, handler)
+	h.hosts = append(h.hosts, hm)
+
+	// los hosts con más etiquetas fijas se evalúan primero
+	sort.SliceStable(h.hosts, func(i, j int) bool {
+		return literalLabels(h.hosts[i].labels) > literalLabels(h.hosts[j].labels)
+	})
+}
+
+// dispatch envuelve los handlers sin host: si el host de la petición coincide con un host con
+// parámetros que tiene la ruta, la atiende su mux; si no, se usa la ruta sin host igual que
+// ServeMux prefiere los patrones con host exacto
+func (h *hostRouter) dispatch(fallback http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		hosts := h.hosts
+		h.mu.RUnlock()
+
+		if len(hosts) == 0 {
+			fallback(w, r)
+			return
+		}
+
+		requestLabels := strings.Split(requestHost(r), ".")
+		for _, hm := range hosts {
+			params, ok := matchHost(hm.labels, requestLabels)
+			if !ok {
+				continue
+			}
+
+			if _, pattern := hm.mux.Handler(r); pattern == "" {
+				continue
+			}
+
+			for name, value := range params {
+				r.SetPathValue(name, value)
+			}
+
+			hm.mux.ServeHTTP(w, r)
+			return
+		}
+
+		fallback(w, r)
+	}
+}
+
+// matchHost compara el host por etiquetas, "{name}" captura una etiqueta completa
+func matchHost(patternLabels []string, requestLabels []string) (map[string]string, bool) {
+	if len(patternLabels) != len(requestLabels) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, label := range patternLabels {
+		if strings.HasPrefix(label, "{") && strings.HasSuffix(label, "}") {
+			if requestLabels[i] == "" {
+				return nil, false
+			}
+
+			params[label[1:len(label)-1]] = requestLabels[i]
+			continue
+		}
+
+		if label != requestLabels[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func literalLabels(labels []string) int {
+	count := 0
+	for _, label := range labels {
+		if !strings.HasPrefix(label, "{") {
+			count++
+		}
+	}
+
+	return count
+}
+
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
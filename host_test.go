@@ -0,0 +1,88 @@
+package goroutes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+func hostAction(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + ":" + r.PathValue("tenant")))
+	}
+}
+
+func serveHost(mux *http.ServeMux, host, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Host = host
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, r)
+
+	return rec
+}
+
+func wildcardHostGroups() []definitions.RouteGroup {
+	return []definitions.RouteGroup{
+		{Routes: []interface{}{
+			definitions.Route{Path: "/shared", Method: http.MethodGet, ExcludeMiddlewares: withoutAccess, Action: hostAction("plain")},
+		}},
+		{Host: "{tenant}.example.com", Routes: []interface{}{
+			definitions.Route{Path: "/", Method: http.MethodGet, ExcludeMiddlewares: withoutAccess, Action: hostAction("root")},
+			definitions.Route{Path: "/dash", Method: http.MethodGet, ExcludeMiddlewares: withoutAccess, Action: hostAction("dash")},
+			definitions.Route{Path: "/shared", Method: http.MethodGet, ExcludeMiddlewares: withoutAccess, Action: hostAction("shared")},
+		}},
+	}
+}
+
+func TestWildcardHostWithoutCatchAll(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(t *testing.T, mux *http.ServeMux)
+		other string
+	}{
+		{"not found disabled", func(t *testing.T, mux *http.ServeMux) {
+			t.Setenv("GOROUTES_NOT_FOUND_ENABLED", "false")
+		}, ""},
+		{"app serves root", func(t *testing.T, mux *http.ServeMux) {
+			mux.HandleFunc("/", hostAction("app"))
+		}, "app:"},
+		{"catch-all enabled", func(t *testing.T, mux *http.ServeMux) {}, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			c.setup(t, mux)
+			LoadRoutes(wildcardHostGroups(), mux, nil)
+
+			expected := map[string]string{
+				"acme.example.com /dash":   "dash:acme",
+				"acme.example.com /":       "root:acme",
+				"acme.example.com /shared": "shared:acme",
+				"other.com /shared":        "plain:",
+			}
+
+			for request, body := range expected {
+				host, target, _ := strings.Cut(request, " ")
+				rec := serveHost(mux, host, target)
+				if rec.Code != http.StatusOK || rec.Body.String() != body {
+					t.Fatalf("%s: expected %q, got %d %q", request, body, rec.Code, rec.Body.String())
+				}
+			}
+
+			// en otros hosts el path del host con parámetros no existe
+			rec := serveHost(mux, "other.com", "/dash")
+			if c.other == "" && rec.Code != http.StatusNotFound {
+				t.Fatalf("expected 404 for other hosts, got %d", rec.Code)
+			}
+
+			if c.other != "" && rec.Body.String() != c.other {
+				t.Fatalf("expected the app handler for other hosts, got %d %q", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Nemutagk/golog"
+)
+
+// ShutdownHook es una función que se ejecuta al apagar el servidor, después de drenar las
+// peticiones en curso (ej. vaciar buffers de logs o cerrar conexiones)
+type ShutdownHook func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	hook ShutdownHook
+}
+
+var (
+	mu    sync.Mutex
+	hooks []namedHook
+	// se guarda el valor negado para que el servicio esté listo por defecto cuando no se usa goroutes.Server
+	notReady atomic.Bool
+)
+
+// RegisterShutdownHook registra una función a ejecutar al apagar el servidor, los hooks se
+// ejecutan en orden inverso al de registro
+func RegisterShutdownHook(name string, hook ShutdownHook) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	hooks = append(hooks, namedHook{name: name, hook: hook})
+}
+
+// RunShutdownHooks ejecuta los hooks registrados, un error en un hook no detiene a los demás
+func RunShutdownHooks(ctx context.Context) error {
+	mu.Lock()
+	list := make([]namedHook, len(hooks))
+	copy(list, hooks)
+	mu.Unlock()
+
+	var errs []error
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := list[i].hook(ctx); err != nil {
+			golog.Error(ctx, "Error running shutdown hook:", list[i].name, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetReady marca si el servicio está listo para recibir tráfico
+func SetReady(value bool) {
+	notReady.Store(!value)
+}
+
+// IsReady indica si el servicio está listo para recibir tráfico
+func IsReady() bool {
+	return !notReady.Load()
+}
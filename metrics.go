@@ -0,0 +1,30 @@
+package goroutes
+
+import (
+	"net/http"
+
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/metrics"
+	"github.com/Nemutagk/goroutes/middlewares"
+)
+
+// MetricsRoutes retorna la ruta que expone las métricas en formato Prometheus (GOROUTES_METRICS_PATH,
+// /metrics por defecto). LoadRoutes la registra cuando GOROUTES_METRICS_ENABLED está activo, también
+// puede agregarse manualmente dentro de un grupo protegido
+func MetricsRoutes() definitions.RouteGroup {
+	exclude := []definitions.Middleware{middlewares.AccessMiddleware, middlewares.MetricsMiddleware}
+
+	return definitions.RouteGroup{
+		Prefix: "/",
+		Routes: []interface{}{
+			definitions.Route{
+				Path:               goenvars.GetEnv("GOROUTES_METRICS_PATH", "/metrics"),
+				Method:             http.MethodGet,
+				Action:             metrics.Handler,
+				ExcludeMiddlewares: &exclude,
+				Priority:           definitions.PriorityCritical,
+			},
+		},
+	}
+}
@@ -0,0 +1,320 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/helper/http/wr"
+)
+
+// AccessLogEntry contiene los campos del Combined Log Format más la información propia de goroutes
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	RemoteIp  string    `json:"remote_ip"`
+	User      string    `json:"user,omitempty"`
+	Method    string    `json:"method"`
+	Uri       string    `json:"uri"`
+	Protocol  string    `json:"protocol"`
+	Status    int       `json:"status"`
+	Bytes     int       `json:"bytes"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	RequestId string    `json:"request_id,omitempty"`
+	LatencyMs float64   `json:"latency_ms"`
+	Route     string    `json:"route"`
+	// Principal solo contiene los campos del principal listados en
+	// GOROUTES_ACCESS_LOG_PRINCIPAL_FIELDS (vacío por defecto para no registrar datos personales)
+	Principal map[string]any `json:"principal,omitempty"`
+}
+
+// AccessLogSink recibe una entrada por cada petición terminada
+type AccessLogSink interface {
+	Write(ctx context.Context, entry AccessLogEntry) error
+}
+
+// SampleRule define qué proporción (Rate entre 0 y 1) de las peticiones que coinciden se registra.
+// Una regla coincide si el path inicia con PathPrefix (cuando se define) y el status está entre
+// MinStatus y MaxStatus (cuando se definen)
+type SampleRule struct {
+	PathPrefix string
+	MinStatus  int
+	MaxStatus  int
+	Rate       float64
+}
+
+func (rule SampleRule) matches(entry AccessLogEntry) bool {
+	if rule.PathPrefix != "" && !strings.HasPrefix(entry.Uri, rule.PathPrefix) {
+		return false
+	}
+
+	if rule.MinStatus > 0 && entry.Status < rule.MinStatus {
+		return false
+	}
+
+	if rule.MaxStatus > 0 && entry.Status > rule.MaxStatus {
+		return false
+	}
+
+	return true
+}
+
+// JsonAccessLogSink escribe una línea JSON por petición, se aplica la primera regla de muestreo
+// que coincida y sin reglas se registran todas las peticiones
+type JsonAccessLogSink struct {
+	mu     sync.Mutex
+	Writer io.Writer
+	Rules  []SampleRule
+}
+
+func NewJsonAccessLogSink(w io.Writer, rules ...SampleRule) *JsonAccessLogSink {
+	return &JsonAccessLogSink{Writer: w, Rules: rules}
+}
+
+func (s *JsonAccessLogSink) Write(ctx context.Context, entry AccessLogEntry) error {
+	for _, rule := range s.Rules {
+		if rule.matches(entry) {
+			if rule.Rate <= 0 || (rule.Rate < 1 && rand.Float64() >= rule.Rate) {
+				return nil
+			}
+			break
+		}
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.Writer.Write(append(line, '\n'))
+	return err
+}
+
+// ParseSampleRules interpreta reglas con el formato "selector:rate" separadas por comas, el selector
+// puede ser una clase de status ("2xx"), un status ("404") o un prefijo de path ("/healthz")
+func ParseSampleRules(value string) []SampleRule {
+	rules := []SampleRule{}
+
+	for _, item := range splitList(value) {
+		idx := strings.LastIndex(item, ":")
+		if idx <= 0 {
+			continue
+		}
+
+		rate, err := strconv.ParseFloat(item[idx+1:], 64)
+		if err != nil {
+			continue
+		}
+
+		selector := item[:idx]
+		rule := SampleRule{Rate: rate}
+
+		switch {
+		case strings.HasPrefix(selector, "/"):
+			rule.PathPrefix = selector
+		case len(selector) == 3 && strings.HasSuffix(selector, "xx"):
+			class, err := strconv.Atoi(selector[:1])
+			if err != nil {
+				continue
+			}
+			rule.MinStatus = class * 100
+			rule.MaxStatus = class*100 + 99
+		default:
+			status, err := strconv.Atoi(selector)
+			if err != nil {
+				continue
+			}
+			rule.MinStatus = status
+			rule.MaxStatus = status
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+var (
+	accessLogSinksMu   sync.Mutex
+	accessLogSinks     []AccessLogSink
+	accessLogSinksOnce sync.Once
+)
+
+// AddAccessLogSink registra un sink adicional, AccessMiddleware y AccessLogMiddleware escriben en
+// todos los sinks registrados
+func AddAccessLogSink(sink AccessLogSink) {
+	loadAccessLogSinks()
+
+	accessLogSinksMu.Lock()
+	defer accessLogSinksMu.Unlock()
+
+	accessLogSinks = append(accessLogSinks, sink)
+}
+
+// loadAccessLogSinks registra el sink JSON en stdout cuando GOROUTES_ACCESS_LOG_STDOUT está activo
+func loadAccessLogSinks() {
+	accessLogSinksOnce.Do(func() {
+		if goenvars.GetEnvBool("GOROUTES_ACCESS_LOG_STDOUT", false) {
+			accessLogSinks = append(accessLogSinks, NewJsonAccessLogSink(os.Stdout, ParseSampleRules(goenvars.GetEnv("GOROUTES_ACCESS_LOG_SAMPLING", ""))...))
+		}
+	})
+}
+
+func getAccessLogSinks() []AccessLogSink {
+	loadAccessLogSinks()
+
+	accessLogSinksMu.Lock()
+	defer accessLogSinksMu.Unlock()
+
+	return append([]AccessLogSink{}, accessLogSinks...)
+}
+
+func writeAccessLogSinks(ctx context.Context, entry AccessLogEntry) {
+	for _, sink := range getAccessLogSinks() {
+		if err := sink.Write(ctx, entry); err != nil {
+			golog.Error(ctx, "Error writing access log sink:", err)
+			accessLogWriteFailures.Inc()
+		}
+	}
+}
+
+// AccessLogMiddleware registra la petición en los sinks configurados sin usar Mongo, es la
+// alternativa a AccessMiddleware para entornos sin base de datos de logs. Si no hay sinks
+// registrados escribe en stdout
+func AccessLogMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, state := withAccessLogState(r.Context())
+		ctx = context.WithValue(ctx, definitions.RequestIDKey, getRequestId(r))
+
+		wrEnv := wr.Acquire(w)
+		defer wr.Release(wrEnv)
+		statusCode := http.StatusInternalServerError
+
+		defer func() {
+			entry := newAccessLogEntry(ctx, r, route, statusCode, wrEnv.BytesWritten(), start, state)
+			if len(getAccessLogSinks()) == 0 {
+				defaultAccessLogSink.Write(ctx, entry)
+				return
+			}
+
+			writeAccessLogSinks(ctx, entry)
+		}()
+
+		next(wrEnv.Writer(), r.WithContext(ctx))
+		statusCode = wrEnv.GetStatus()
+	}
+}
+
+var defaultAccessLogSink = NewJsonAccessLogSink(os.Stdout)
+
+// accessLogState permite que los middlewares internos (ej. AuthMiddleware) informen el principal
+// autenticado al middleware de logs que está más afuera en la cadena
+type accessLogState struct {
+	mu        sync.Mutex
+	principal any
+}
+
+type accessLogStateKey struct{}
+
+func withAccessLogState(ctx context.Context) (context.Context, *accessLogState) {
+	if state, ok := ctx.Value(accessLogStateKey{}).(*accessLogState); ok {
+		return ctx, state
+	}
+
+	state := &accessLogState{}
+	return context.WithValue(ctx, accessLogStateKey{}, state), state
+}
+
+// setPrincipal guarda el principal autenticado en el contexto y lo informa al log de acceso
+func setPrincipal(ctx context.Context, principal any) context.Context {
+	if state, ok := ctx.Value(accessLogStateKey{}).(*accessLogState); ok {
+		state.mu.Lock()
+		state.principal = principal
+		state.mu.Unlock()
+	}
+
+	return context.WithValue(ctx, definitions.AuthKey, principal)
+}
+
+func newAccessLogEntry(ctx context.Context, r *http.Request, route definitions.Route, statusCode int, bytes int, start time.Time, state *accessLogState) AccessLogEntry {
+	clientIp, _ := getRealIp(r)
+	requestId, _ := ctx.Value(definitions.RequestIDKey).(string)
+
+	var principal any
+	if state != nil {
+		state.mu.Lock()
+		principal = state.principal
+		state.mu.Unlock()
+	}
+
+	return AccessLogEntry{
+		Time:      start,
+		RemoteIp:  clientIp,
+		User:      principalName(principal),
+		Method:    r.Method,
+		Uri:       r.URL.RequestURI(),
+		Protocol:  r.Proto,
+		Status:    statusCode,
+		Bytes:     bytes,
+		Referer:   r.Referer(),
+		UserAgent: r.UserAgent(),
+		RequestId: requestId,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Route:     route.Pattern,
+		Principal: principalFields(principal),
+	}
+}
+
+// principalFields filtra el principal con la lista de campos permitidos
+func principalFields(principal any) map[string]any {
+	fields := goenvars.GetEnv("GOROUTES_ACCESS_LOG_PRINCIPAL_FIELDS", "")
+	values, ok := principal.(map[string]any)
+	if fields == "" || !ok {
+		return nil
+	}
+
+	allowed := map[string]any{}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if value, exists := values[field]; exists && field != "" {
+			allowed[field] = value
+		}
+	}
+
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	return allowed
+}
+
+// principalName obtiene el identificador del principal para el campo "user" del log
+func principalName(principal any) string {
+	values, ok := principal.(map[string]any)
+	if !ok {
+		return ""
+	}
+
+	for _, key := range []string{"username", "sub", "email", "common_name", "client_id", "id"} {
+		if value, ok := values[key].(string); ok && value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -11,14 +11,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/Nemutagk/godb"
 	"github.com/Nemutagk/godb/definitions/db"
 	"github.com/Nemutagk/goenvars"
 	"github.com/Nemutagk/golog"
 	"github.com/Nemutagk/goroutes/definitions"
 	"github.com/Nemutagk/goroutes/helper"
 	"github.com/Nemutagk/goroutes/helper/http/wr"
-	"github.com/gofrs/uuid"
+	"github.com/Nemutagk/goroutes/tracing"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -33,7 +32,8 @@ const ACCESS_CODE_TOKEN_EXPIRED = "0406"
 
 func AccessMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
 	return func(res http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
+		start := time.Now()
+		ctx := r.Context()
 
 		golog.Log(ctx, "==================> AccessMiddleware called")
 		clientIp, _ := getRealIp(r)
@@ -51,6 +51,7 @@ func AccessMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn
 		if validateBlackList(ctx, dbConn, clientIp, res, r, route) {
 			golog.Warning(ctx, "IP is blacklisted:", clientIp)
 			golog.Log(ctx, "==================> AccessMiddleware END")
+			blacklistHits.Inc()
 			res.Header().Set("X-Request-Error", ACCESS_CODE_BLACKLISTED)
 			res.WriteHeader(http.StatusForbidden)
 			res.Write([]byte("Access denied"))
@@ -62,6 +63,7 @@ func AccessMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn
 		if validateRequest(ctx, dbConn, clientIp, res, r, route) {
 			golog.Warning(ctx, "IP is blacklisted by request 401/403:", clientIp)
 			golog.Log(ctx, "==================> AccessMiddleware END")
+			blacklistHits.Inc()
 			res.Header().Set("X-Request-Error", ACCESS_CODE_FORBIDDEN)
 			res.WriteHeader(http.StatusForbidden)
 			res.Write([]byte("Access denied"))
@@ -71,13 +73,28 @@ func AccessMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn
 		golog.Log(ctx, "==================> AccessMiddleware Medio")
 		registerAccessLog(ctx, dbConn, res, r, route, http.StatusOK)
 
-		wrEnv := wr.NewResponseRecorder(res)
+		wrEnv := wr.Acquire(res)
+		defer wr.Release(wrEnv)
+		nextCtx, state := withAccessLogState(context.WithValue(r.Context(), definitions.RequestIDKey, ctx.Value(definitions.RequestIDKey)))
+
+		// si el handler entra en pánico registramos el 500 en el log de acceso y dejamos que
+		// RecoveryMiddleware se encargue de la respuesta
+		defer func() {
+			if rec := recover(); rec != nil {
+				updateRequestStatus(ctx, dbConn, clientIp, http.StatusInternalServerError)
+				writeAccessLogSinks(ctx, newAccessLogEntry(ctx, r, route, http.StatusInternalServerError, wrEnv.BytesWritten(), start, state))
+				panic(rec)
+			}
+		}()
+
+		next(wrEnv.Writer(), r.WithContext(nextCtx))
 
-		next(wrEnv, r)
+		// además de Mongo, la petición se envía a los sinks adicionales (ej. JSON en stdout)
+		writeAccessLogSinks(ctx, newAccessLogEntry(ctx, r, route, wrEnv.GetStatus(), wrEnv.BytesWritten(), start, state))
 
 		if wrEnv.GetStatus() != http.StatusOK {
 			golog.Log(ctx, "==================> AccessMiddleware END")
-			updateRequestStatus(dbConn, clientIp, wrEnv.GetStatus())
+			updateRequestStatus(ctx, dbConn, clientIp, wrEnv.GetStatus())
 		}
 	}
 }
@@ -148,7 +165,7 @@ func getRealIp(r *http.Request) (string, string) {
 
 func getConnection(ctx context.Context, dbListConn map[string]db.DbConnection, wr http.ResponseWriter) *mongo.Database {
 	db_conn_name := goenvars.GetEnv("DB_LOGS_CONNECTION", "logs")
-	conn, err_con := godb.InitConnections(dbListConn).GetConnection(db_conn_name)
+	conn, err_con := helper.DbConnections(dbListConn).GetConnection(db_conn_name)
 
 	if err_con != nil {
 		golog.Error(ctx, "Error getting database connection:", err_con)
@@ -168,10 +185,7 @@ func generateRequestId(ctx context.Context, r *http.Request, clientIp string, ro
 	golog.Log(ctx, "Route path:"+r.URL.String())
 	golog.Log(ctx, "Route method:"+route.Method)
 
-	requestId := uuid.Must(uuid.NewV7()).String()
-	if rid := r.Header.Get("X-RequestKb-ID"); rid != "" {
-		requestId = rid
-	}
+	requestId := getRequestId(r)
 
 	ctx = context.WithValue(ctx, definitions.RequestIDKey, requestId)
 	golog.Log(ctx, "Generated request ID:", requestId)
@@ -180,6 +194,9 @@ func generateRequestId(ctx context.Context, r *http.Request, clientIp string, ro
 }
 
 func registerAccessLog(ctx context.Context, dbConn *mongo.Database, wr http.ResponseWriter, r *http.Request, route definitions.Route, codeStatus int) {
+	ctx, span := startMongoSpan(ctx, "insert", "access")
+	defer span.End()
+
 	coll := dbConn.Collection("access")
 	clientIp, clientRealIp := getRealIp(r)
 
@@ -209,16 +226,21 @@ func registerAccessLog(ctx context.Context, dbConn *mongo.Database, wr http.Resp
 	golog.Log(ctx, "request", body_save)
 
 	if _, err := coll.InsertOne(ctx, body_save); err != nil {
+		span.SetError(err)
 		golog.Error(ctx, "Error inserting access log:", err)
+		accessLogWriteFailures.Inc()
 		wr.WriteHeader(http.StatusInternalServerError)
 		wr.Write([]byte("Internal server error"))
 	}
 }
 
 func validateBlackList(ctx context.Context, dbConn *mongo.Database, clientIp string, wr http.ResponseWriter, r *http.Request, route definitions.Route) bool {
+	spanCtx, span := startMongoSpan(ctx, "count", "ip_black_list")
+	defer span.End()
+
 	coll := dbConn.Collection("ip_black_list")
 
-	exists, err := coll.CountDocuments(ctx, bson.M{
+	exists, err := coll.CountDocuments(spanCtx, bson.M{
 		"ip": clientIp,
 		"$or": []bson.M{
 			{"expired_at": bson.M{"$eq": nil}},
@@ -231,6 +253,7 @@ func validateBlackList(ctx context.Context, dbConn *mongo.Database, clientIp str
 			return false // No blacklisted IP found
 		}
 
+		span.SetError(err)
 		registerAccessLog(ctx, dbConn, wr, r, route, 403)
 		golog.Error(ctx, "Error checking black list:", err)
 
@@ -246,9 +269,12 @@ func validateBlackList(ctx context.Context, dbConn *mongo.Database, clientIp str
 	return false
 }
 
-func addBlackList(dbConn *mongo.Database, clientIp string, expiredTime *time.Time) error {
+func addBlackList(ctx context.Context, dbConn *mongo.Database, clientIp string, expiredTime *time.Time) error {
+	ctx, span := startMongoSpan(ctx, "insert", "ip_black_list")
+	defer span.End()
+
 	coll := dbConn.Collection("ip_black_list")
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := coll.InsertOne(ctx, map[string]interface{}{
@@ -257,6 +283,7 @@ func addBlackList(dbConn *mongo.Database, clientIp string, expiredTime *time.Tim
 	})
 
 	if err != nil {
+		span.SetError(err)
 		golog.Error(ctx, "Error inserting black list log:", err)
 		return err
 	}
@@ -269,7 +296,8 @@ func validateRequest(ctx context.Context, dbConn *mongo.Database, clientIp strin
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	accessList, err := collAccess.Find(ctx, bson.M{
+	findCtx, span := startMongoSpan(ctx, "find", "access")
+	accessList, err := collAccess.Find(findCtx, bson.M{
 		"ip":         clientIp,
 		"created_at": bson.M{"$gte": time.Now().Add(-1 * time.Hour)},
 	})
@@ -280,6 +308,9 @@ func validateRequest(ctx context.Context, dbConn *mongo.Database, clientIp strin
 		CreatedAt    time.Time `bson:"created_at"`
 	}
 
+	span.SetError(err)
+	span.End()
+
 	if err != nil {
 		golog.Error(ctx, "Error finding access log:", err)
 		registerAccessLog(ctx, dbConn, wr, r, route, 500)
@@ -310,7 +341,7 @@ func validateRequest(ctx context.Context, dbConn *mongo.Database, clientIp strin
 	if goenvars.GetEnvInt("MAX_DENIED_ACCESS", 3) < count403Request {
 		// IP is blacklisted
 		exp := time.Now().Add((24 * 365) * time.Hour)
-		addBlackList(dbConn, clientIp, &exp)
+		addBlackList(ctx, dbConn, clientIp, &exp)
 		registerAccessLog(ctx, dbConn, wr, r, route, 403)
 		return true
 	}
@@ -318,7 +349,7 @@ func validateRequest(ctx context.Context, dbConn *mongo.Database, clientIp strin
 	if goenvars.GetEnvInt("MAX_ACCESS", 10) < count401Request {
 		// IP is blacklisted
 		exp := time.Now().Add(24 * time.Hour)
-		addBlackList(dbConn, clientIp, &exp)
+		addBlackList(ctx, dbConn, clientIp, &exp)
 		registerAccessLog(ctx, dbConn, wr, r, route, 401)
 		return true
 	}
@@ -326,12 +357,15 @@ func validateRequest(ctx context.Context, dbConn *mongo.Database, clientIp strin
 	return false
 }
 
-func updateRequestStatus(dbConn *mongo.Database, clientIp string, status int) error {
+func updateRequestStatus(ctx context.Context, dbConn *mongo.Database, clientIp string, status int) error {
+	ctx, span := startMongoSpan(context.WithoutCancel(ctx), "update", "access")
+	defer span.End()
+
 	coll := dbConn.Collection("access")
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	request_id := ctx.Value("request_id")
+	request_id := ctx.Value(definitions.RequestIDKey)
 	if request_id == nil {
 		request_id = "--"
 	}
@@ -341,13 +375,26 @@ func updateRequestStatus(dbConn *mongo.Database, clientIp string, status int) er
 		"request_id": request_id,
 	}, bson.M{"$set": bson.M{"response_code": status}})
 	if err != nil {
+		span.SetError(err)
 		golog.Error(ctx, "Error updating access log:", err)
+		accessLogWriteFailures.Inc()
 		return err
 	}
 
 	return nil
 }
 
+// startMongoSpan crea el span hijo de una operación de Mongo del log de acceso, nil si la traza no
+// se exporta
+func startMongoSpan(ctx context.Context, operation string, collection string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.StartSpanIfRecording(ctx, "mongo "+operation+" "+collection, tracing.SpanKindClient)
+	span.SetAttribute("db.system", "mongodb")
+	span.SetAttribute("db.operation.name", operation)
+	span.SetAttribute("db.collection.name", collection)
+
+	return ctx, span
+}
+
 func GetFullRequestURL(r *http.Request) string {
 	// Protocolo
 	proto := r.Header.Get("X-Forwarded-Proto")
@@ -1,17 +1,54 @@
 package middlewares
 
 import (
-	"context"
 	"net/http"
+	"sync"
 
 	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
 	"github.com/Nemutagk/golog"
 	"github.com/Nemutagk/goroutes/definitions"
 	"github.com/Nemutagk/goroutes/helper"
 	"github.com/Nemutagk/goroutes/service"
 )
 
-type contextKey string
+var (
+	authenticatorMu sync.RWMutex
+	authenticator   service.Authenticator
+)
+
+// SetAuthenticator define el Authenticator usado por AuthMiddleware, si no se define (o se define
+// nil) se elige a partir de AUTH_PROVIDER ("account" por defecto o "introspection")
+func SetAuthenticator(a service.Authenticator) {
+	authenticatorMu.Lock()
+	defer authenticatorMu.Unlock()
+
+	authenticator = a
+}
+
+func getAuthenticator() service.Authenticator {
+	authenticatorMu.RLock()
+	a := authenticator
+	authenticatorMu.RUnlock()
+
+	if a != nil {
+		return a
+	}
+
+	authenticatorMu.Lock()
+	defer authenticatorMu.Unlock()
+
+	if authenticator == nil {
+		switch goenvars.GetEnv("AUTH_PROVIDER", "account") {
+		case "introspection":
+			authenticator = service.NewIntrospectionAuthenticator()
+		default:
+			authenticator = service.NewAccountAuthenticator()
+		}
+	}
+
+	return authenticator
+}
 
 func AuthMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -32,15 +69,11 @@ func AuthMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn m
 			return
 		}
 
-		res, err := service.AccountService("/auth/validation", "POST", map[string]string{
-			"token":      token,
-			"app":        route.Auth.App,
-			"permission": route.Auth.Permission,
-		})
+		res, err := getAuthenticator().Authenticate(r.Context(), token, *route.Auth)
 
 		if err != nil {
 			if httpErr, ok := err.(*service.HTTPError); ok {
-				golog.Error(r.Context(), "Error from account service:", httpErr.Status)
+				golog.Error(r.Context(), "Error from authenticator:", httpErr.Status)
 				helper.PrettyPrint(httpErr)
 				golog.Log(r.Context(), "==================> AuthMiddleware END")
 				w.Header().Set("Content-Type", "application/json")
@@ -55,7 +88,7 @@ func AuthMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn m
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), contextKey("auth"), res)
+		ctx := setPrincipal(r.Context(), res)
 		golog.Log(ctx, "==================> AuthMiddleware END")
 
 		next(w, r.WithContext(ctx))
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+type staticAuthenticator string
+
+func (a staticAuthenticator) Authenticate(ctx context.Context, token string, auth definitions.RouteAuth) (any, error) {
+	return string(a), nil
+}
+
+func TestSetAuthenticatorConcurrent(t *testing.T) {
+	t.Cleanup(func() { SetAuthenticator(nil) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetAuthenticator(staticAuthenticator("custom"))
+		}()
+		go func() {
+			defer wg.Done()
+			if getAuthenticator() == nil {
+				t.Error("authenticator must never be nil")
+			}
+		}()
+	}
+	wg.Wait()
+
+	SetAuthenticator(staticAuthenticator("last"))
+	principal, _ := getAuthenticator().Authenticate(context.Background(), "token", definitions.RouteAuth{})
+	if principal != "last" {
+		t.Fatalf("expected the last authenticator, got %v", principal)
+	}
+}
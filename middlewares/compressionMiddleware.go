@@ -0,0 +1,390 @@
+package middlewares
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// CompressionEncoder es un algoritmo de compresión negociable por Accept-Encoding, permite agregar
+// brotli o zstd con RegisterCompressionEncoder sin agregar dependencias a goroutes
+type CompressionEncoder interface {
+	// Encoding es el valor usado en Accept-Encoding/Content-Encoding (ej. "gzip")
+	Encoding() string
+	// NewWriter retorna un writer que comprime hacia w, debe vaciar (flush) los datos al cerrarse
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+type gzipEncoder struct {
+	pool sync.Pool
+}
+
+func (e *gzipEncoder) Encoding() string {
+	return "gzip"
+}
+
+func (e *gzipEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	if gw, ok := e.pool.Get().(*gzip.Writer); ok {
+		gw.Reset(w)
+		return &pooledWriter{WriteCloser: gw, flusher: gw, release: func() { e.pool.Put(gw) }}
+	}
+
+	gw, err := gzip.NewWriterLevel(w, goenvars.GetEnvInt("GOROUTES_COMPRESSION_LEVEL", gzip.DefaultCompression))
+	if err != nil {
+		gw = gzip.NewWriter(w)
+	}
+
+	return &pooledWriter{WriteCloser: gw, flusher: gw, release: func() { e.pool.Put(gw) }}
+}
+
+type deflateEncoder struct {
+	pool sync.Pool
+}
+
+func (e *deflateEncoder) Encoding() string {
+	return "deflate"
+}
+
+func (e *deflateEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	if fw, ok := e.pool.Get().(*flate.Writer); ok {
+		fw.Reset(w)
+		return &pooledWriter{WriteCloser: fw, flusher: fw, release: func() { e.pool.Put(fw) }}
+	}
+
+	fw, err := flate.NewWriter(w, goenvars.GetEnvInt("GOROUTES_COMPRESSION_LEVEL", flate.DefaultCompression))
+	if err != nil {
+		fw, _ = flate.NewWriter(w, flate.DefaultCompression)
+	}
+
+	return &pooledWriter{WriteCloser: fw, flusher: fw, release: func() { e.pool.Put(fw) }}
+}
+
+// pooledWriter regresa el writer al pool al cerrarse y expone Flush para respuestas en streaming
+type pooledWriter struct {
+	io.WriteCloser
+	flusher interface{ Flush() error }
+	release func()
+}
+
+func (p *pooledWriter) Flush() error {
+	return p.flusher.Flush()
+}
+
+func (p *pooledWriter) Close() error {
+	err := p.WriteCloser.Close()
+	p.release()
+	return err
+}
+
+var (
+	compressionMu       sync.RWMutex
+	compressionEncoders = []CompressionEncoder{&gzipEncoder{}, &deflateEncoder{}}
+)
+
+// RegisterCompressionEncoder agrega un algoritmo de compresión, los registrados tienen preferencia
+// sobre gzip y deflate cuando el cliente los acepta con la misma calidad
+func RegisterCompressionEncoder(encoder CompressionEncoder) {
+	compressionMu.Lock()
+	defer compressionMu.Unlock()
+
+	compressionEncoders = append([]CompressionEncoder{encoder}, compressionEncoders...)
+}
+
+// tipos que ya vienen comprimidos y no vale la pena volver a comprimir
+var compressionSkipTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-brotli", "application/x-7z-compressed", "application/x-rar-compressed",
+	"application/pdf", "application/octet-stream",
+}
+
+// CompressionMiddleware comprime la respuesta con el mejor algoritmo aceptado por el cliente.
+// No se comprimen respuestas menores a GOROUTES_COMPRESSION_MIN_SIZE bytes, tipos ya comprimidos
+// ni respuestas que ya traen Content-Encoding. Se desactiva por ruta con "compression": false
+// en Route.MiddlewareParams
+func CompressionMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if enabled, ok := getRouteParam(route, "compression"); ok && enabled == false {
+			next(w, r)
+			return
+		}
+
+		addVary(w.Header(), "Accept-Encoding")
+
+		encoder := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoder == nil || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			rw:      w,
+			encoder: encoder,
+			minSize: goenvars.GetEnvInt("GOROUTES_COMPRESSION_MIN_SIZE", 1024),
+		}
+		defer func() {
+			// con un panic no se completa el body, enviarlo como una respuesta 200 válida evitaría que
+			// RecoveryMiddleware responda el error
+			if rec := recover(); rec != nil {
+				panic(rec)
+			}
+
+			cw.close()
+		}()
+
+		// Hijacker solo se expone si el writer original lo soporta (ej. no en HTTP/2)
+		if canHijack(w) {
+			next(compressHijackWriter{cw}, r)
+			return
+		}
+
+		next(cw, r)
+	}
+}
+
+// negotiateEncoding elige el algoritmo con mayor calidad (q) en Accept-Encoding
+func negotiateEncoding(acceptEncoding string) CompressionEncoder {
+	if acceptEncoding == "" {
+		return nil
+	}
+
+	qualities := map[string]float64{}
+	for _, part := range splitList(acceptEncoding) {
+		name, params, _ := strings.Cut(part, ";")
+		quality := 1.0
+
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.TrimSpace(key) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		qualities[strings.ToLower(strings.TrimSpace(name))] = quality
+	}
+
+	compressionMu.RLock()
+	defer compressionMu.RUnlock()
+
+	var best CompressionEncoder
+	bestQuality := 0.0
+	for _, encoder := range compressionEncoders {
+		quality, exists := qualities[encoder.Encoding()]
+		if !exists {
+			quality, exists = qualities["*"]
+		}
+
+		if exists && quality > bestQuality {
+			best = encoder
+			bestQuality = quality
+		}
+	}
+
+	return best
+}
+
+func addVary(header http.Header, value string) {
+	for _, vary := range header.Values("Vary") {
+		for _, item := range strings.Split(vary, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), value) || strings.TrimSpace(item) == "*" {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", value)
+}
+
+// compressWriter guarda los primeros bytes de la respuesta hasta decidir si se comprime
+// (por tamaño, tipo y status) y después escribe directamente al cliente
+type compressWriter struct {
+	rw          http.ResponseWriter
+	encoder     CompressionEncoder
+	minSize     int
+	status      int
+	buf         []byte
+	decided     bool
+	compressing bool
+	writer      io.WriteCloser
+}
+
+func (cw *compressWriter) Header() http.Header {
+	return cw.rw.Header()
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+
+	// las respuestas informativas (1xx) se envían tal cual
+	if code >= 100 && code < 200 {
+		cw.rw.WriteHeader(code)
+		return
+	}
+
+	cw.status = code
+
+	// respuestas sin body, se envían de inmediato
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.minSize {
+			return len(b), nil
+		}
+
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+
+		return len(b), nil
+	}
+
+	if cw.compressing {
+		return cw.writer.Write(b)
+	}
+
+	return cw.rw.Write(b)
+}
+
+// Flush decide la compresión aunque no se haya alcanzado el tamaño mínimo, para que las
+// respuestas en streaming se envíen de inmediato
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if !cw.decided {
+		cw.decide(true)
+	}
+
+	if cw.compressing {
+		if flusher, ok := cw.writer.(interface{ Flush() error }); ok {
+			flusher.Flush()
+		}
+	}
+
+	http.NewResponseController(cw.rw).Flush()
+}
+
+func (cw *compressWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.rw).Hijack()
+}
+
+type compressHijackWriter struct{ *compressWriter }
+
+func (cw compressHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return cw.hijack() }
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.rw
+}
+
+// decide envía los headers comprimiendo si el tamaño (cuando sizeReached) y el tipo lo permiten
+func (cw *compressWriter) decide(sizeReached bool) error {
+	cw.decided = true
+	header := cw.rw.Header()
+
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	cw.compressing = sizeReached &&
+		cw.status != http.StatusNoContent &&
+		cw.status != http.StatusNotModified &&
+		cw.status != http.StatusPartialContent &&
+		header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == "" &&
+		compressibleType(header.Get("Content-Type"))
+
+	if cw.compressing {
+		header.Set("Content-Encoding", cw.encoder.Encoding())
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+
+		// un ETag fuerte identifica los bytes exactos, al comprimir se marca como débil
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		cw.writer = cw.encoder.NewWriter(cw.rw)
+	}
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.rw.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.compressing {
+		_, err = cw.writer.Write(buf)
+	} else {
+		_, err = cw.rw.Write(buf)
+	}
+
+	return err
+}
+
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		// si el handler no escribió nada no se envían headers, net/http responde 200 vacío
+		if cw.status == 0 && len(cw.buf) == 0 {
+			return
+		}
+
+		cw.decide(len(cw.buf) >= cw.minSize)
+	}
+
+	if cw.compressing {
+		cw.writer.Close()
+	}
+}
+
+func compressibleType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType == "" {
+		return false
+	}
+
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		return true
+	}
+
+	// los tipos del entorno se leen en otra lista, agregarlos a compressionSkipTypes escribiría sobre
+	// el arreglo compartido entre peticiones
+	for _, skipTypes := range [][]string{compressionSkipTypes, splitList(goenvars.GetEnv("GOROUTES_COMPRESSION_SKIP_TYPES", ""))} {
+		for _, skip := range skipTypes {
+			if strings.HasPrefix(contentType, strings.ToLower(skip)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,174 @@
+package middlewares
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+var largeBody = strings.Repeat("compressible text ", 200)
+
+func serveCompressed(handler http.HandlerFunc, method string, acceptEncoding string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	rec := httptest.NewRecorder()
+	CompressionMiddleware(handler, definitions.Route{}, nil)(rec, r)
+
+	return rec
+}
+
+func writeBody(contentType string, status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		if status != 0 {
+			w.WriteHeader(status)
+		}
+		io.WriteString(w, body)
+	}
+}
+
+func TestCompressionGzip(t *testing.T) {
+	rec := serveCompressed(writeBody("text/plain", 0, largeBody), http.MethodGet, "deflate;q=0.5, gzip")
+
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected gzip with Vary, got %q %q", rec.Header().Get("Content-Encoding"), rec.Header().Get("Vary"))
+	}
+
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil || string(body) != largeBody {
+		t.Fatalf("expected the original body after decompressing, got %d bytes (%v)", len(body), err)
+	}
+}
+
+func TestCompressionSkipped(t *testing.T) {
+	t.Setenv("GOROUTES_COMPRESSION_MIN_SIZE", "100")
+	t.Setenv("GOROUTES_COMPRESSION_SKIP_TYPES", "application/X-Custom")
+
+	alreadyEncoded := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		writeBody("text/plain", 0, largeBody)(w, r)
+	}
+
+	cases := []struct {
+		name           string
+		handler        http.HandlerFunc
+		method         string
+		acceptEncoding string
+		body           string
+	}{
+		{"below min size", writeBody("text/plain", 0, strings.Repeat("x", 99)), http.MethodGet, "gzip", strings.Repeat("x", 99)},
+		{"image", writeBody("image/png", 0, largeBody), http.MethodGet, "gzip", largeBody},
+		{"octet stream", writeBody("application/octet-stream", 0, largeBody), http.MethodGet, "gzip", largeBody},
+		{"env skip type", writeBody("application/x-custom; charset=utf-8", 0, largeBody), http.MethodGet, "gzip", largeBody},
+		{"already encoded", alreadyEncoded, http.MethodGet, "gzip", largeBody},
+		{"not accepted", writeBody("text/plain", 0, largeBody), http.MethodGet, "", largeBody},
+		{"identity only", writeBody("text/plain", 0, largeBody), http.MethodGet, "gzip;q=0", largeBody},
+		{"head", writeBody("text/plain", 0, ""), http.MethodHead, "gzip", ""},
+		{"no content", writeBody("text/plain", http.StatusNoContent, ""), http.MethodGet, "gzip", ""},
+		{"not modified", writeBody("text/plain", http.StatusNotModified, ""), http.MethodGet, "gzip", ""},
+	}
+
+	for _, c := range cases {
+		rec := serveCompressed(c.handler, c.method, c.acceptEncoding)
+
+		if encoding := rec.Header().Get("Content-Encoding"); encoding == "gzip" || encoding == "deflate" {
+			t.Fatalf("%s: expected no compression, got %q", c.name, encoding)
+		}
+
+		if rec.Body.String() != c.body {
+			t.Fatalf("%s: expected the body unchanged, got %d bytes", c.name, rec.Body.Len())
+		}
+	}
+
+	if rec := serveCompressed(writeBody("text/plain", http.StatusNotModified, ""), http.MethodGet, "gzip"); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+}
+
+func TestCompressionSkipTypesNotShared(t *testing.T) {
+	skipTypes := make([]string, len(compressionSkipTypes), len(compressionSkipTypes)+4)
+	copy(skipTypes, compressionSkipTypes)
+
+	original := compressionSkipTypes
+	compressionSkipTypes = skipTypes
+	t.Cleanup(func() { compressionSkipTypes = original })
+
+	t.Setenv("GOROUTES_COMPRESSION_SKIP_TYPES", "text/csv")
+	compressibleType("text/csv")
+
+	if len(compressionSkipTypes) != len(original) || compressionSkipTypes[:cap(compressionSkipTypes)][len(original)] != "" {
+		t.Fatal("the environment skip types must not be written into compressionSkipTypes")
+	}
+
+	t.Setenv("GOROUTES_COMPRESSION_SKIP_TYPES", "")
+	if !compressibleType("text/csv") {
+		t.Fatal("text/csv must be compressible without GOROUTES_COMPRESSION_SKIP_TYPES")
+	}
+}
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (h hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestCompressionHijacker(t *testing.T) {
+	cases := []struct {
+		name     string
+		writer   http.ResponseWriter
+		expected bool
+	}{
+		{"hijackable", hijackableRecorder{httptest.NewRecorder()}, true},
+		{"not hijackable", httptest.NewRecorder(), false},
+	}
+
+	for _, c := range cases {
+		var hijacker bool
+		handler := CompressionMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			_, hijacker = w.(http.Hijacker)
+		}, definitions.Route{}, nil)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		handler(c.writer, r)
+
+		if hijacker != c.expected {
+			t.Fatalf("%s: expected Hijacker %v, got %v", c.name, c.expected, hijacker)
+		}
+	}
+}
+
+func TestCompressionPanic(t *testing.T) {
+	handler := RecoveryMiddleware(CompressionMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("handler failed")
+	}, definitions.Route{}, nil), definitions.Route{}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Encoding") != "" || strings.Contains(rec.Body.String(), "partial") {
+		t.Fatalf("expected the recovery 500 without the partial body, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,284 @@
+package middlewares
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/helper/http/wr"
+)
+
+const CONCURRENCY_MODE_FIXED = "fixed"
+const CONCURRENCY_MODE_AIMD = "aimd"
+
+type ConcurrencyConfig struct {
+	// Mode es "fixed" (límite constante) o "aimd" (el límite crece de forma aditiva mientras la
+	// latencia está por debajo de TargetLatency y se reduce de forma multiplicativa al superarla)
+	Mode string
+	// MaxInFlight es el máximo de peticiones en ejecución (el límite inicial en modo aimd)
+	MaxInFlight int
+	// MinInFlight es el límite mínimo al que puede bajar el modo aimd
+	MinInFlight int
+	// QueueSize es el máximo de peticiones en espera de un lugar, un valor negativo desactiva la cola
+	QueueSize int
+	// QueueTimeout es el tiempo máximo que una petición espera en la cola
+	QueueTimeout time.Duration
+	// TargetLatency es la latencia objetivo del modo aimd
+	TargetLatency time.Duration
+	// RetryAfter es el valor del header Retry-After en las peticiones descartadas
+	RetryAfter time.Duration
+}
+
+type concurrencyWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// ConcurrencyLimiter limita las peticiones en ejecución y mantiene una cola acotada por prioridad
+type ConcurrencyLimiter struct {
+	mu       sync.Mutex
+	config   ConcurrencyConfig
+	limit    float64
+	inFlight int
+	queued   int
+	queues   map[definitions.Priority]*list.List
+}
+
+func NewConcurrencyLimiter(config ConcurrencyConfig) *ConcurrencyLimiter {
+	if config.MaxInFlight <= 0 {
+		config.MaxInFlight = 100
+	}
+
+	if config.MinInFlight <= 0 || config.MinInFlight > config.MaxInFlight {
+		config.MinInFlight = 1
+	}
+
+	if config.Mode == "" {
+		config.Mode = CONCURRENCY_MODE_FIXED
+	}
+
+	// mismos valores por defecto que LoadConcurrencyConfigFromEnv
+	if config.QueueSize == 0 {
+		config.QueueSize = 100
+	}
+
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = time.Second
+	}
+
+	if config.TargetLatency <= 0 {
+		config.TargetLatency = 500 * time.Millisecond
+	}
+
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+
+	return &ConcurrencyLimiter{
+		config: config,
+		limit:  float64(config.MaxInFlight),
+		queues: map[definitions.Priority]*list.List{},
+	}
+}
+
+// LoadConcurrencyConfigFromEnv genera la configuración a partir de las variables GOROUTES_CONCURRENCY_*
+func LoadConcurrencyConfigFromEnv() ConcurrencyConfig {
+	return ConcurrencyConfig{
+		Mode:          goenvars.GetEnv("GOROUTES_CONCURRENCY_MODE", CONCURRENCY_MODE_FIXED),
+		MaxInFlight:   goenvars.GetEnvInt("GOROUTES_CONCURRENCY_MAX", 100),
+		MinInFlight:   goenvars.GetEnvInt("GOROUTES_CONCURRENCY_MIN", 1),
+		QueueSize:     goenvars.GetEnvInt("GOROUTES_CONCURRENCY_QUEUE_SIZE", 100),
+		QueueTimeout:  time.Duration(goenvars.GetEnvInt("GOROUTES_CONCURRENCY_QUEUE_TIMEOUT_MS", 1000)) * time.Millisecond,
+		TargetLatency: time.Duration(goenvars.GetEnvInt("GOROUTES_CONCURRENCY_TARGET_LATENCY_MS", 500)) * time.Millisecond,
+		RetryAfter:    time.Duration(goenvars.GetEnvInt("GOROUTES_CONCURRENCY_RETRY_AFTER", 1)) * time.Second,
+	}
+}
+
+// Acquire obtiene un lugar para ejecutar la petición, si no hay lugar la petición espera en la
+// cola hasta QueueTimeout. Las peticiones de prioridad crítica no se limitan y las de prioridad
+// baja solo pueden ocupar la mitad de la cola
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, priority definitions.Priority) bool {
+	// nextWaiter solo revisa las colas de las prioridades definidas
+	priority = min(max(priority, definitions.PriorityLow), definitions.PriorityCritical)
+
+	l.mu.Lock()
+
+	if priority == definitions.PriorityCritical || l.inFlight < l.currentLimit() {
+		l.inFlight++
+		l.mu.Unlock()
+		return true
+	}
+
+	maxQueue := l.config.QueueSize
+	if priority == definitions.PriorityLow {
+		maxQueue = maxQueue / 2
+	}
+
+	if l.queued >= maxQueue {
+		l.mu.Unlock()
+		return false
+	}
+
+	waiter := &concurrencyWaiter{ready: make(chan struct{})}
+	queue, exists := l.queues[priority]
+	if !exists {
+		queue = list.New()
+		l.queues[priority] = queue
+	}
+	element := queue.PushBack(waiter)
+	l.queued++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.config.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-waiter.ready:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// el lugar pudo ser asignado justo cuando se agotó el tiempo de espera
+	if waiter.granted {
+		return true
+	}
+
+	queue.Remove(element)
+	l.queued--
+
+	return false
+}
+
+// Release libera el lugar de una petición, en modo aimd ajusta el límite con la latencia y el
+// resultado de la petición y asigna los lugares libres a la cola en orden de prioridad
+func (l *ConcurrencyLimiter) Release(latency time.Duration, failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+
+	if l.config.Mode == CONCURRENCY_MODE_AIMD {
+		if failed || latency > l.config.TargetLatency {
+			l.limit = math.Max(float64(l.config.MinInFlight), l.limit*0.9)
+		} else {
+			l.limit = math.Min(float64(l.config.MaxInFlight), l.limit+1/l.limit)
+		}
+	}
+
+	for l.inFlight < l.currentLimit() {
+		waiter := l.nextWaiter()
+		if waiter == nil {
+			break
+		}
+
+		waiter.granted = true
+		l.inFlight++
+		close(waiter.ready)
+	}
+}
+
+func (l *ConcurrencyLimiter) nextWaiter() *concurrencyWaiter {
+	for _, priority := range []definitions.Priority{definitions.PriorityCritical, definitions.PriorityHigh, definitions.PriorityNormal, definitions.PriorityLow} {
+		queue, exists := l.queues[priority]
+		if !exists || queue.Len() == 0 {
+			continue
+		}
+
+		l.queued--
+		return queue.Remove(queue.Front()).(*concurrencyWaiter)
+	}
+
+	return nil
+}
+
+func (l *ConcurrencyLimiter) currentLimit() int {
+	return int(l.limit)
+}
+
+// InFlight retorna el número de peticiones en ejecución
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Limit retorna el límite actual de peticiones en ejecución
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.currentLimit()
+}
+
+var (
+	concurrencyLimiterMu sync.RWMutex
+	concurrencyLimiter   *ConcurrencyLimiter
+)
+
+// SetConcurrencyLimiter define el limitador usado por ConcurrencyMiddleware, si no se define se
+// crea uno con LoadConcurrencyConfigFromEnv
+func SetConcurrencyLimiter(limiter *ConcurrencyLimiter) {
+	concurrencyLimiterMu.Lock()
+	defer concurrencyLimiterMu.Unlock()
+
+	concurrencyLimiter = limiter
+}
+
+func getConcurrencyLimiter() *ConcurrencyLimiter {
+	concurrencyLimiterMu.RLock()
+	limiter := concurrencyLimiter
+	concurrencyLimiterMu.RUnlock()
+
+	if limiter != nil {
+		return limiter
+	}
+
+	concurrencyLimiterMu.Lock()
+	defer concurrencyLimiterMu.Unlock()
+
+	if concurrencyLimiter == nil {
+		concurrencyLimiter = NewConcurrencyLimiter(LoadConcurrencyConfigFromEnv())
+	}
+
+	return concurrencyLimiter
+}
+
+// ConcurrencyMiddleware limita las peticiones concurrentes del servicio usando Route.Priority,
+// cuando no hay lugar responde 503 con Retry-After. Con GOROUTES_CONCURRENCY_ENABLED se agrega a
+// la cadena por defecto, agregado en los middlewares de una ruta o grupo queda en la misma
+// posición: antes de AccessMiddleware y de los demás middlewares de la ruta
+func ConcurrencyMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiter := getConcurrencyLimiter()
+
+		if !limiter.Acquire(r.Context(), route.Priority) {
+			golog.Warning(r.Context(), "Request shed by concurrency limiter:", r.Method, r.URL.Path)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limiter.config.RetryAfter.Seconds()))))
+			errorResponse(w, "Service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		start := time.Now()
+		wrEnv := wr.Acquire(w)
+		defer wr.Release(wrEnv)
+		failed := true
+
+		defer func() {
+			limiter.Release(time.Since(start), failed)
+		}()
+
+		next(wrEnv.Writer(), r)
+		failed = wrEnv.GetStatus() >= http.StatusInternalServerError
+	}
+}
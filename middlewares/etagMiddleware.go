@@ -0,0 +1,269 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// ETagResolver obtiene la versión actual del recurso (ETag ya formateado con SetETag/FormatETag y
+// fecha de modificación) para validar If-Match/If-Unmodified-Since antes de ejecutar el handler
+type ETagResolver func(r *http.Request) (etag string, lastModified time.Time, err error)
+
+// FormatETag genera el valor del header ETag a partir de una versión del modelo de dominio
+func FormatETag(tag string, weak bool) string {
+	tag = `"` + strings.Trim(tag, `"`) + `"`
+	if weak {
+		return "W/" + tag
+	}
+
+	return tag
+}
+
+// SetETag define el ETag de la respuesta desde el handler, ETagMiddleware lo usa en lugar de
+// calcular el hash del body
+func SetETag(w http.ResponseWriter, tag string, weak bool) {
+	w.Header().Set("ETag", FormatETag(tag, weak))
+}
+
+// SetLastModified define el header Last-Modified usado para If-Modified-Since/If-Unmodified-Since
+func SetLastModified(w http.ResponseWriter, lastModified time.Time) {
+	w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+}
+
+// CheckPreconditions valida If-Match e If-Unmodified-Since contra la versión actual del recurso,
+// si alguna falla responde 412 y retorna false. Pensado para handlers de PUT/PATCH/DELETE
+func CheckPreconditions(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			errorResponse(w, "Precondition failed", http.StatusPreconditionFailed)
+			return false
+		}
+	} else if ifUnmodified := r.Header.Get("If-Unmodified-Since"); ifUnmodified != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifUnmodified)
+		if err == nil && lastModified.Truncate(time.Second).After(since) {
+			errorResponse(w, "Precondition failed", http.StatusPreconditionFailed)
+			return false
+		}
+	}
+
+	return true
+}
+
+// ETagMiddleware agrega ETag a las respuestas 200 de GET/HEAD (hash del body si el handler no
+// definió uno) y responde 304 con If-None-Match/If-Modified-Since. En métodos que modifican el
+// recurso valida If-Match/If-Unmodified-Since (412) cuando la ruta define un ETagResolver.
+//
+// Parámetros por ruta (Route.MiddlewareParams):
+//   - etag_weak: genera ETags débiles (W/"...")
+//   - etag_resolver: ETagResolver (o func(*http.Request) (string, time.Time, error)) con la
+//     versión actual del recurso, cualquier otro tipo entra en pánico al registrar la ruta
+func ETagMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	resolver := etagResolver(route)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if resolver != nil {
+				etag, lastModified, err := resolver(r)
+				if err != nil {
+					golog.Error(r.Context(), "Error resolving ETag:", err)
+					errorResponse(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+
+				if !CheckPreconditions(w, r, etag, lastModified) {
+					return
+				}
+			}
+
+			next(w, r)
+			return
+		}
+
+		weak, _ := getRouteParam(route, "etag_weak")
+		ew := &etagWriter{rw: w}
+
+		next(ew, r)
+
+		if ew.passthrough {
+			return
+		}
+
+		header := w.Header()
+		if ew.status == 0 {
+			ew.status = http.StatusOK
+		}
+
+		if ew.status != http.StatusOK {
+			ew.commit()
+			return
+		}
+
+		if header.Get("ETag") == "" {
+			hash := sha256.Sum256(ew.buf.Bytes())
+			header.Set("ETag", FormatETag(hex.EncodeToString(hash[:16]), weak == true))
+		}
+
+		if notModified(r, header) {
+			for _, key := range []string{"Content-Type", "Content-Length", "Content-Encoding"} {
+				header.Del(key)
+			}
+
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		ew.commit()
+	}
+}
+
+// etagResolver obtiene el parámetro etag_resolver de la ruta. Un valor de otro tipo desactivaría
+// sin aviso las validaciones 412, por eso es un error de configuración
+func etagResolver(route definitions.Route) ETagResolver {
+	value, ok := getRouteParam(route, "etag_resolver")
+	if !ok || value == nil {
+		return nil
+	}
+
+	switch resolver := value.(type) {
+	case ETagResolver:
+		return resolver
+	case func(r *http.Request) (string, time.Time, error):
+		return resolver
+	}
+
+	panic(fmt.Sprintf("etag_resolver of route %s must be a middlewares.ETagResolver, got %T", route.Pattern, value))
+}
+
+// notModified evalúa If-None-Match y, si no viene, If-Modified-Since (RFC 9110 13.2.2)
+func notModified(r *http.Request, header http.Header) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return matchETag(ifNoneMatch, header.Get("ETag"), true)
+	}
+
+	ifModifiedSince := r.Header.Get("If-Modified-Since")
+	lastModified := header.Get("Last-Modified")
+	if ifModifiedSince == "" || lastModified == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
+}
+
+// matchETag compara el header (lista de ETags o "*") con el ETag actual, la comparación débil
+// ignora el prefijo W/ (If-None-Match) y la fuerte exige que ninguno sea débil (If-Match)
+func matchETag(headerValue string, etag string, weakComparison bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(headerValue) == "*" {
+		return true
+	}
+
+	for _, candidate := range splitList(headerValue) {
+		if weakComparison {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// etagWriter guarda la respuesta para calcular el ETag, si el handler hace Flush (streaming)
+// se envía lo guardado y el resto de la respuesta pasa directo sin ETag
+type etagWriter struct {
+	rw          http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (ew *etagWriter) Header() http.Header {
+	return ew.rw.Header()
+}
+
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.passthrough {
+		ew.rw.WriteHeader(code)
+		return
+	}
+
+	if code >= 100 && code < 200 {
+		ew.rw.WriteHeader(code)
+		return
+	}
+
+	if ew.status == 0 {
+		ew.status = code
+	}
+}
+
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if ew.passthrough {
+		return ew.rw.Write(b)
+	}
+
+	if ew.status == 0 {
+		ew.status = http.StatusOK
+	}
+
+	return ew.buf.Write(b)
+}
+
+func (ew *etagWriter) Flush() {
+	if !ew.passthrough {
+		ew.commit()
+		ew.passthrough = true
+	}
+
+	http.NewResponseController(ew.rw).Flush()
+}
+
+func (ew *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	ew.passthrough = true
+	return http.NewResponseController(ew.rw).Hijack()
+}
+
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.rw
+}
+
+func (ew *etagWriter) commit() {
+	if ew.status == 0 {
+		ew.status = http.StatusOK
+	}
+
+	ew.rw.WriteHeader(ew.status)
+	if ew.buf.Len() > 0 {
+		ew.rw.Write(ew.buf.Bytes())
+	}
+	ew.buf.Reset()
+}
@@ -0,0 +1,155 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+var etagModified = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func etagRoute(params map[string]interface{}) definitions.Route {
+	return definitions.Route{Pattern: "/resource", MiddlewareParams: &params}
+}
+
+func serveETag(route definitions.Route, handler http.HandlerFunc, method string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/resource", nil)
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	ETagMiddleware(handler, route, nil)(rec, r)
+
+	return rec
+}
+
+func resourceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"id":1}`))
+}
+
+func TestETagGenerated(t *testing.T) {
+	rec := serveETag(etagRoute(nil), resourceHandler, http.MethodGet, nil)
+	etag := rec.Header().Get("ETag")
+
+	if rec.Code != http.StatusOK || etag == "" || rec.Body.String() != `{"id":1}` {
+		t.Fatalf("expected 200 with ETag and body, got %d %q %q", rec.Code, etag, rec.Body.String())
+	}
+
+	if again := serveETag(etagRoute(nil), resourceHandler, http.MethodGet, nil); again.Header().Get("ETag") != etag {
+		t.Fatal("the same body must generate the same ETag")
+	}
+
+	weak := serveETag(etagRoute(map[string]interface{}{"etag_weak": true}), resourceHandler, http.MethodGet, nil)
+	if weak.Header().Get("ETag") != "W/"+etag {
+		t.Fatalf("expected weak ETag W/%s, got %q", etag, weak.Header().Get("ETag"))
+	}
+}
+
+func TestETagNotModified(t *testing.T) {
+	etag := serveETag(etagRoute(nil), resourceHandler, http.MethodGet, nil).Header().Get("ETag")
+
+	withLastModified := func(w http.ResponseWriter, r *http.Request) {
+		SetETag(w, "v7", false)
+		SetLastModified(w, etagModified)
+		resourceHandler(w, r)
+	}
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		headers map[string]string
+		status  int
+	}{
+		{"if-none-match", resourceHandler, http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"if-none-match weak", resourceHandler, http.MethodGet, map[string]string{"If-None-Match": "W/" + etag}, http.StatusNotModified},
+		{"if-none-match list", resourceHandler, http.MethodHead, map[string]string{"If-None-Match": `"other", ` + etag}, http.StatusNotModified},
+		{"if-none-match changed", resourceHandler, http.MethodGet, map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"handler etag", withLastModified, http.MethodGet, map[string]string{"If-None-Match": `"v7"`}, http.StatusNotModified},
+		{"if-modified-since", withLastModified, http.MethodGet, map[string]string{"If-Modified-Since": etagModified.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"if-modified-since older", withLastModified, http.MethodGet, map[string]string{"If-Modified-Since": etagModified.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK},
+		{"if-none-match wins", withLastModified, http.MethodGet, map[string]string{"If-None-Match": `"v6"`, "If-Modified-Since": etagModified.Format(http.TimeFormat)}, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		rec := serveETag(etagRoute(nil), c.handler, c.method, c.headers)
+		if rec.Code != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.status, rec.Code)
+		}
+
+		if c.status == http.StatusNotModified && (rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "") {
+			t.Fatalf("%s: 304 must not have body or Content-Type", c.name)
+		}
+	}
+}
+
+func TestETagOnlyForOk(t *testing.T) {
+	rec := serveETag(etagRoute(nil), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}, http.MethodGet, map[string]string{"If-None-Match": "*"})
+
+	if rec.Code != http.StatusCreated || rec.Header().Get("ETag") != "" || rec.Body.String() != "created" {
+		t.Fatalf("expected 201 without ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestETagPreconditions(t *testing.T) {
+	var named ETagResolver = func(r *http.Request) (string, time.Time, error) {
+		return FormatETag("v2", false), etagModified, nil
+	}
+
+	unnamed := func(r *http.Request) (string, time.Time, error) {
+		return FormatETag("v2", false), etagModified, nil
+	}
+
+	failing := func(r *http.Request) (string, time.Time, error) {
+		return "", time.Time{}, errors.New("database down")
+	}
+
+	cases := []struct {
+		name     string
+		resolver interface{}
+		headers  map[string]string
+		status   int
+	}{
+		{"named match", named, map[string]string{"If-Match": `"v2"`}, http.StatusNoContent},
+		{"named mismatch", named, map[string]string{"If-Match": `"v1"`}, http.StatusPreconditionFailed},
+		{"unnamed mismatch", unnamed, map[string]string{"If-Match": `"v1"`}, http.StatusPreconditionFailed},
+		{"weak if-match", unnamed, map[string]string{"If-Match": `W/"v2"`}, http.StatusPreconditionFailed},
+		{"if-match any", unnamed, map[string]string{"If-Match": "*"}, http.StatusNoContent},
+		{"unmodified since", unnamed, map[string]string{"If-Unmodified-Since": etagModified.Format(http.TimeFormat)}, http.StatusNoContent},
+		{"modified since", unnamed, map[string]string{"If-Unmodified-Since": etagModified.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusPreconditionFailed},
+		{"without preconditions", unnamed, nil, http.StatusNoContent},
+		{"resolver error", failing, map[string]string{"If-Match": `"v2"`}, http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		route := etagRoute(map[string]interface{}{"etag_resolver": c.resolver})
+		rec := serveETag(route, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}, http.MethodPut, c.headers)
+
+		if rec.Code != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.status, rec.Code)
+		}
+	}
+}
+
+func TestETagInvalidResolver(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for an etag_resolver of another type")
+		}
+	}()
+
+	ETagMiddleware(resourceHandler, etagRoute(map[string]interface{}{
+		"etag_resolver": func(r *http.Request) string { return "v1" },
+	}), nil)
+}
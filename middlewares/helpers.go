@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Nemutagk/goerrors"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// errorResponse escribe el cuerpo de error estándar del proyecto (goerrors.GError en JSON)
+func errorResponse(w http.ResponseWriter, message string, statusCode int) {
+	gErr := goerrors.NewGError(message, statusCode, nil, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(gErr.GetStatusCode())
+	w.Write([]byte(gErr.ToJson()))
+}
+
+// getRouteParam obtiene un parámetro definido en Route.MiddlewareParams
+func getRouteParam(route definitions.Route, key string) (interface{}, bool) {
+	if route.MiddlewareParams == nil {
+		return nil, false
+	}
+
+	value, exists := (*route.MiddlewareParams)[key]
+	return value, exists
+}
+
+// getRouteParamList obtiene un parámetro de Route.MiddlewareParams como lista de strings,
+// acepta []string, []interface{} o un string separado por comas
+func getRouteParamList(route definitions.Route, key string) ([]string, bool) {
+	value, exists := getRouteParam(route, key)
+	if !exists || value == nil {
+		return nil, false
+	}
+
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				list = append(list, str)
+			}
+		}
+		return list, true
+	case string:
+		return splitList(v), true
+	}
+
+	return nil, false
+}
+
+// splitList separa una lista por comas eliminando espacios y elementos vacíos
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// canHijack recorre los writers con Unwrap igual que http.ResponseController, los wrappers solo
+// exponen http.Hijacker si el writer original lo soporta
+func canHijack(rw http.ResponseWriter) bool {
+	for rw != nil {
+		switch t := rw.(type) {
+		case http.Hijacker:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = t.Unwrap()
+		default:
+			return false
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/golog"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "goroutes-middlewares")
+	if err != nil {
+		panic(err)
+	}
+
+	golog.Init(map[string]db.DbConnection{}, golog.WithFileDriver(filepath.Join(dir, "middlewares.log"), false))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/helper/http/wr"
+	"github.com/Nemutagk/goroutes/metrics"
+)
+
+var (
+	requestsTotal = metrics.NewCounterVec("goroutes_http_requests_total",
+		"Total de peticiones HTTP por ruta, método y clase de status.", "route", "method", "status")
+	requestDuration = metrics.NewHistogramVec("goroutes_http_request_duration_seconds",
+		"Latencia de las peticiones HTTP en segundos.", metrics.DefaultBuckets, "route", "method", "status")
+	requestsInFlight = metrics.NewGaugeVec("goroutes_http_requests_in_flight",
+		"Peticiones HTTP en ejecución.")
+	blacklistHits = metrics.NewCounterVec("goroutes_blacklist_hits_total",
+		"Peticiones rechazadas por IP en lista negra.")
+	accessLogWriteFailures = metrics.NewCounterVec("goroutes_access_log_write_failures_total",
+		"Errores al escribir el log de acceso.")
+)
+
+// MetricsMiddleware registra el total, la latencia y las peticiones en curso por patrón de ruta
+// (Route.Pattern, no la URL real), método y clase de status (2xx, 4xx, ...). Los métodos que no
+// son estándar se registran como OTHER, los Mount y el catch-all aceptan cualquier método y el
+// cliente podría generar series sin límite
+func MetricsMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestsInFlight.Inc()
+
+		wrEnv := wr.Acquire(w)
+		defer wr.Release(wrEnv)
+		statusClass := "5xx"
+
+		defer func() {
+			requestsInFlight.Dec()
+			requestsTotal.Inc(route.Pattern, metricMethod(r.Method), statusClass)
+			requestDuration.Observe(time.Since(start).Seconds(), route.Pattern, metricMethod(r.Method), statusClass)
+		}()
+
+		next(wrEnv.Writer(), r)
+		statusClass = strconv.Itoa(wrEnv.GetStatus()/100) + "xx"
+	}
+}
+
+// metricMethod limita la etiqueta method a los métodos de net/http
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+
+	return "OTHER"
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/metrics"
+)
+
+func TestMetricsMethodLabel(t *testing.T) {
+	handler := MetricsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, definitions.Route{Pattern: "/metrics-test/"}, nil)
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete, "PROPFIND", "RANDOM-1", "RANDOM-2"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest(method, "/metrics-test/x", nil))
+	}
+
+	var output bytes.Buffer
+	metrics.WriteText(&output)
+	text := output.String()
+
+	for _, method := range []string{"GET", "DELETE", "OTHER"} {
+		if !strings.Contains(text, `route="/metrics-test/",method="`+method+`"`) {
+			t.Fatalf("expected a series for %s, got:\n%s", method, text)
+		}
+	}
+
+	for _, method := range []string{"PROPFIND", "RANDOM-1", "RANDOM-2"} {
+		if strings.Contains(text, `method="`+method+`"`) {
+			t.Fatalf("non-standard method %s must be recorded as OTHER", method)
+		}
+	}
+
+	if !strings.Contains(text, `goroutes_http_requests_total{route="/metrics-test/",method="OTHER",status="2xx"} 3`) {
+		t.Fatalf("expected 3 requests labeled OTHER, got:\n%s", text)
+	}
+}
@@ -0,0 +1,346 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// MtlsConfig define cómo se validan los certificados de cliente en MtlsMiddleware
+type MtlsConfig struct {
+	// RootCAs es el pool de CAs con el que se validan los certificados de cliente
+	RootCAs *x509.CertPool
+	// AllowedSubjects son patrones (path.Match) que se comparan contra el CN y los SAN
+	// (DNS, URI y email) del certificado, si está vacío se acepta cualquier certificado válido
+	AllowedSubjects []string
+	// TrustedProxies son las redes desde las que se acepta el certificado reenviado en ForwardedHeader
+	TrustedProxies []*net.IPNet
+	// ForwardedHeader es el header donde el proxy reenvía el certificado cuando termina el TLS
+	ForwardedHeader string
+}
+
+var (
+	mtlsConfigMu     sync.RWMutex
+	mtlsConfig       *MtlsConfig
+	mtlsConfigLoaded bool
+)
+
+// SetMtlsConfig define la configuración de MtlsMiddleware, si no se define se carga desde las
+// variables de entorno MTLS_*
+func SetMtlsConfig(config MtlsConfig) {
+	mtlsConfigMu.Lock()
+	defer mtlsConfigMu.Unlock()
+
+	mtlsConfig = &config
+	mtlsConfigLoaded = true
+}
+
+func getMtlsConfig() *MtlsConfig {
+	mtlsConfigMu.RLock()
+	config, loaded := mtlsConfig, mtlsConfigLoaded
+	mtlsConfigMu.RUnlock()
+
+	if loaded {
+		return config
+	}
+
+	mtlsConfigMu.Lock()
+	defer mtlsConfigMu.Unlock()
+
+	if !mtlsConfigLoaded {
+		config, err := LoadMtlsConfigFromEnv()
+		if err != nil {
+			golog.Error(context.Background(), "Error loading mTLS config:", err)
+		}
+		mtlsConfig = config
+		mtlsConfigLoaded = true
+	}
+
+	return mtlsConfig
+}
+
+// LoadMtlsConfigFromEnv genera la configuración a partir de MTLS_CA_FILES, MTLS_ALLOWED_SUBJECTS,
+// MTLS_TRUSTED_PROXIES y MTLS_FORWARDED_HEADER
+func LoadMtlsConfigFromEnv() (*MtlsConfig, error) {
+	config := &MtlsConfig{
+		AllowedSubjects: splitList(goenvars.GetEnv("MTLS_ALLOWED_SUBJECTS", "")),
+		ForwardedHeader: goenvars.GetEnv("MTLS_FORWARDED_HEADER", "X-Forwarded-Client-Cert"),
+	}
+
+	caFiles := splitList(goenvars.GetEnv("MTLS_CA_FILES", ""))
+	if len(caFiles) > 0 {
+		config.RootCAs = x509.NewCertPool()
+		for _, file := range caFiles {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return config, err
+			}
+
+			if !config.RootCAs.AppendCertsFromPEM(content) {
+				return config, errors.New("no certificates found in CA file: " + file)
+			}
+		}
+	}
+
+	for _, proxy := range splitList(goenvars.GetEnv("MTLS_TRUSTED_PROXIES", "")) {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return config, err
+		}
+		config.TrustedProxies = append(config.TrustedProxies, network)
+	}
+
+	return config, nil
+}
+
+// MtlsMiddleware autentica la petición con el certificado de cliente, ya sea el presentado en la
+// conexión TLS o el reenviado por un proxy de confianza. El principal se guarda en definitions.AuthKey.
+//
+// Parámetros por ruta (Route.MiddlewareParams):
+//   - mtls_allowed_subjects: reemplaza los patrones de sujetos permitidos para la ruta
+func MtlsMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		golog.Log(r.Context(), "==================> MtlsMiddleware called")
+
+		config := getMtlsConfig()
+		if config == nil || config.RootCAs == nil {
+			golog.Error(r.Context(), "mTLS middleware without CA pool configured")
+			golog.Log(r.Context(), "==================> MtlsMiddleware END")
+			errorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		certs, err := clientCertificates(r, config)
+		if err != nil {
+			golog.Warning(r.Context(), "Client certificate not provided:", err.Error())
+			golog.Log(r.Context(), "==================> MtlsMiddleware END")
+			errorResponse(w, "Client certificate required", http.StatusUnauthorized)
+			return
+		}
+
+		leaf := certs[0]
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		if _, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         config.RootCAs,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}); err != nil {
+			golog.Warning(r.Context(), "Invalid client certificate:", err.Error())
+			golog.Log(r.Context(), "==================> MtlsMiddleware END")
+			errorResponse(w, "Invalid client certificate", http.StatusUnauthorized)
+			return
+		}
+
+		allowed, ok := getRouteParamList(route, "mtls_allowed_subjects")
+		if !ok {
+			allowed = config.AllowedSubjects
+		}
+
+		if !certificateAllowed(leaf, allowed) {
+			golog.Warning(r.Context(), "Client certificate subject not allowed:", leaf.Subject.String())
+			golog.Log(r.Context(), "==================> MtlsMiddleware END")
+			errorResponse(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		ctx := setPrincipal(r.Context(), certificatePrincipal(leaf))
+		golog.Log(ctx, "==================> MtlsMiddleware END")
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// clientCertificates obtiene la cadena de certificados del cliente, primero de la conexión TLS
+// y si no existe del header reenviado por un proxy de confianza
+func clientCertificates(r *http.Request, config *MtlsConfig) ([]*x509.Certificate, error) {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates, nil
+	}
+
+	header := r.Header.Get(config.ForwardedHeader)
+	if header == "" {
+		return nil, errors.New("no client certificate")
+	}
+
+	// el header solo se acepta si la conexión viene directamente de un proxy de confianza,
+	// por eso se usa RemoteAddr y no X-Forwarded-For
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	trusted := false
+	for _, network := range config.TrustedProxies {
+		if ip != nil && network.Contains(ip) {
+			trusted = true
+			break
+		}
+	}
+
+	if !trusted {
+		return nil, errors.New("forwarded client certificate from untrusted proxy: " + host)
+	}
+
+	return parseForwardedCertificate(header)
+}
+
+// parseForwardedCertificate acepta el formato de Envoy (X-Forwarded-Client-Cert con Cert="..." o
+// Chain="...") y el PEM url-encoded que envían otros proxies (ej. $ssl_client_escaped_cert de nginx)
+func parseForwardedCertificate(header string) ([]*x509.Certificate, error) {
+	rawPem := ""
+
+	if strings.HasPrefix(header, "-----BEGIN") || strings.HasPrefix(header, "%2D") || strings.HasPrefix(header, "%2d") {
+		rawPem = header
+	} else {
+		// si hay varios elementos (uno por proxy) el primero corresponde al cliente original
+		element := splitQuoted(header, ',')[0]
+		for _, pair := range splitQuoted(element, ';') {
+			key, value, found := strings.Cut(pair, "=")
+			if !found {
+				continue
+			}
+
+			key = strings.ToLower(strings.TrimSpace(key))
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+
+			if key == "chain" || (key == "cert" && rawPem == "") {
+				rawPem = value
+			}
+		}
+	}
+
+	if rawPem == "" {
+		return nil, errors.New("no certificate in forwarded header")
+	}
+
+	// PathUnescape conserva los "+" del base64 sin escapar, QueryUnescape los convierte en espacios
+	decoded, err := url.PathUnescape(rawPem)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []*x509.Certificate{}
+	rest := []byte(decoded)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("invalid certificate in forwarded header")
+	}
+
+	return certs, nil
+}
+
+// splitQuoted separa el texto por el separador indicado ignorando los que estén entre comillas
+func splitQuoted(value string, separator rune) []string {
+	parts := []string{}
+	inQuotes := false
+	start := 0
+
+	for i, char := range value {
+		switch {
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == separator && !inQuotes:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, value[start:])
+}
+
+func certificateIdentities(cert *x509.Certificate) []string {
+	identities := []string{}
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	identities = append(identities, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+
+	return identities
+}
+
+func certificateAllowed(cert *x509.Certificate, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, identity := range certificateIdentities(cert) {
+		for _, pattern := range allowed {
+			if matched, err := path.Match(pattern, identity); err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func certificatePrincipal(cert *x509.Certificate) map[string]any {
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	uris := make([]string, 0, len(cert.URIs))
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	return map[string]any{
+		"type":        "mtls",
+		"subject":     cert.Subject.String(),
+		"common_name": cert.Subject.CommonName,
+		"issuer":      cert.Issuer.String(),
+		"dns_names":   cert.DNSNames,
+		"emails":      cert.EmailAddresses,
+		"uris":        uris,
+		"serial":      cert.SerialNumber.String(),
+		"fingerprint": hex.EncodeToString(fingerprint[:]),
+		"expires_at":  cert.NotAfter,
+	}
+}
@@ -0,0 +1,199 @@
+package middlewares
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  string
+}
+
+// newTestCertificate genera un certificado firmado por parent, sin parent es una CA autofirmada
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate, usages []x509.ExtKeyUsage) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  usages,
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+		template.DNSNames = []string{commonName}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCertificate{
+		cert: cert,
+		key:  key,
+		pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+}
+
+func setTestMtlsConfig(t *testing.T, ca *testCertificate, allowed []string) {
+	t.Helper()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	// httptest.NewRequest usa 192.0.2.1 como RemoteAddr
+	_, trusted, _ := net.ParseCIDR("192.0.2.0/24")
+
+	SetMtlsConfig(MtlsConfig{
+		RootCAs:         roots,
+		AllowedSubjects: allowed,
+		TrustedProxies:  []*net.IPNet{trusted},
+		ForwardedHeader: "X-Forwarded-Client-Cert",
+	})
+
+	t.Cleanup(func() {
+		mtlsConfigMu.Lock()
+		mtlsConfig, mtlsConfigLoaded = nil, false
+		mtlsConfigMu.Unlock()
+	})
+}
+
+func serveMtls(header string, remoteAddr string) (int, any) {
+	var principal any
+	handler := MtlsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		principal = r.Context().Value(definitions.AuthKey)
+	}, definitions.Route{}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if remoteAddr != "" {
+		r.RemoteAddr = remoteAddr
+	}
+	r.Header.Set("X-Forwarded-Client-Cert", header)
+
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+
+	return rec.Code, principal
+}
+
+func TestParseForwardedCertificate(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil, nil)
+	leaf := newTestCertificate(t, "client.example.com", ca, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+	chain := leaf.pem + ca.pem
+
+	if !strings.Contains(chain, "+") {
+		t.Fatal("the test chain must contain '+' in its base64")
+	}
+
+	// algunos proxies solo escapan los caracteres que no pueden ir en un header
+	partial := strings.NewReplacer("\n", "%0A", " ", "%20", "=", "%3D").Replace(chain)
+
+	cases := []struct {
+		name   string
+		header string
+		certs  int
+	}{
+		{"xfcc cert", `By=spiffe://proxy;Hash=abc;Cert="` + url.PathEscape(leaf.pem) + `";Subject="CN=client"`, 1},
+		{"xfcc chain", `Hash=abc;Cert="` + url.PathEscape(leaf.pem) + `";Chain="` + url.PathEscape(chain) + `"`, 2},
+		{"xfcc first element", `Cert="` + url.PathEscape(leaf.pem) + `",Cert="` + url.PathEscape(ca.pem) + `"`, 1},
+		{"xfcc partially escaped", `Chain="` + partial + `"`, 2},
+		{"escaped pem", url.PathEscape(chain), 2},
+		{"partially escaped pem", partial, 2},
+		{"raw pem", chain, 2},
+	}
+
+	for _, c := range cases {
+		certs, err := parseForwardedCertificate(c.header)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		if len(certs) != c.certs || !certs[0].Equal(leaf.cert) {
+			t.Fatalf("%s: expected %d certificates starting with the leaf, got %d", c.name, c.certs, len(certs))
+		}
+	}
+
+	for _, header := range []string{`Hash=abc;Subject="CN=client"`, "%2D%2D%2D%2D%2DBEGIN", `Cert="not-a-certificate"`} {
+		if _, err := parseForwardedCertificate(header); err == nil {
+			t.Fatalf("expected an error for %q", header)
+		}
+	}
+}
+
+func TestMtlsMiddleware(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil, nil)
+	otherCa := newTestCertificate(t, "Other CA", nil, nil)
+	leaf := newTestCertificate(t, "client.example.com", ca, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+	foreign := newTestCertificate(t, "client.example.com", otherCa, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+	serverOnly := newTestCertificate(t, "client.example.com", ca, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	other := newTestCertificate(t, "other.example.com", ca, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+
+	setTestMtlsConfig(t, ca, []string{"client.example.com"})
+
+	xfcc := func(cert *testCertificate) string {
+		return `Cert="` + url.PathEscape(cert.pem) + `"`
+	}
+
+	status, principal := serveMtls(xfcc(leaf), "")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	if claims, ok := principal.(map[string]any); !ok || claims["common_name"] != "client.example.com" {
+		t.Fatalf("expected the certificate principal, got %#v", principal)
+	}
+
+	cases := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		status     int
+	}{
+		{"chain from another CA", xfcc(foreign), "", http.StatusUnauthorized},
+		{"forged chain", `Chain="` + url.PathEscape(foreign.pem+ca.pem) + `"`, "", http.StatusUnauthorized},
+		{"without client auth usage", xfcc(serverOnly), "", http.StatusUnauthorized},
+		{"untrusted proxy", xfcc(leaf), "203.0.113.10:4000", http.StatusUnauthorized},
+		{"missing certificate", "", "", http.StatusUnauthorized},
+		{"subject not allowed", xfcc(other), "", http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		if status, _ := serveMtls(c.header, c.remoteAddr); status != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.status, status)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/helper"
+	"github.com/Nemutagk/goroutes/helper/http/wr"
+)
+
+// RecoveryMiddleware recupera los panics de la cadena de middlewares y del handler, registra el
+// valor y el stack con el request ID y responde con el error estándar si aún no se enviaron headers
+func RecoveryMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), definitions.RequestIDKey, getRequestId(r))
+		r = r.WithContext(ctx)
+
+		wrEnv := wr.Acquire(w)
+		defer wr.Release(wrEnv)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			// http.ErrAbortHandler es la forma estándar de abortar una respuesta, se deja pasar
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			golog.Error(ctx, "Panic recovered:", fmt.Sprint(rec), "Route:", r.Method, r.URL.Path, string(debug.Stack()))
+
+			if wrEnv.WroteHeader() {
+				golog.Warning(ctx, "Headers already sent, unable to write error response")
+				return
+			}
+
+			errorResponse(wrEnv, "Internal server error", http.StatusInternalServerError)
+		}()
+
+		next(wrEnv.Writer(), r)
+	}
+}
+
+// getRequestId obtiene el request ID del contexto, del header X-RequestKb-ID o genera uno nuevo
+func getRequestId(r *http.Request) string {
+	if rid, ok := r.Context().Value(definitions.RequestIDKey).(string); ok && rid != "" {
+		return rid
+	}
+
+	if rid := r.Header.Get("X-RequestKb-ID"); rid != "" {
+		return rid
+	}
+
+	return helper.GenerateUuid()
+}
@@ -0,0 +1,310 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/helper"
+)
+
+const SIGNATURE_HEADER = "X-Signature"
+const SIGNATURE_TIMESTAMP_HEADER = "X-Signature-Timestamp"
+const SIGNATURE_NONCE_HEADER = "X-Signature-Nonce"
+const SIGNATURE_HEADERS_HEADER = "X-Signature-Headers"
+
+const SIGNATURE_VERSION = "v1"
+
+// NonceStore registra los nonces ya utilizados para evitar peticiones repetidas (replay),
+// Seen debe retornar true si el nonce ya fue usado y registrarlo en caso contrario
+type NonceStore interface {
+	Seen(nonce string, expiresAt time.Time) bool
+}
+
+type memoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{nonces: map[string]time.Time{}}
+}
+
+func (s *memoryNonceStore) Seen(nonce string, expiresAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if exp, exists := s.nonces[nonce]; exists && exp.After(now) {
+		return true
+	}
+
+	// limpiamos los nonces expirados para que el mapa no crezca indefinidamente
+	for key, exp := range s.nonces {
+		if !exp.After(now) {
+			delete(s.nonces, key)
+		}
+	}
+
+	s.nonces[nonce] = expiresAt
+	return false
+}
+
+var (
+	signatureNonceStoreMu sync.RWMutex
+	signatureNonceStore   NonceStore = NewMemoryNonceStore()
+)
+
+// SetSignatureNonceStore reemplaza el almacén de nonces, útil cuando hay varias instancias
+// del servicio y se necesita un almacén compartido. Con nil se vuelve al almacén en memoria
+func SetSignatureNonceStore(store NonceStore) {
+	signatureNonceStoreMu.Lock()
+	defer signatureNonceStoreMu.Unlock()
+
+	if store == nil {
+		store = NewMemoryNonceStore()
+	}
+
+	signatureNonceStore = store
+}
+
+func getSignatureNonceStore() NonceStore {
+	signatureNonceStoreMu.RLock()
+	defer signatureNonceStoreMu.RUnlock()
+
+	return signatureNonceStore
+}
+
+// SignatureMiddleware verifica la firma HMAC-SHA256 de la petición.
+//
+// Parámetros por ruta (Route.MiddlewareParams):
+//   - signature_secrets: secretos válidos para la ruta ([]string o string separado por comas)
+//   - signature_headers: headers que obligatoriamente deben formar parte de la firma
+//
+// El body se lee completo antes de validar la firma, SIGNATURE_MAX_BODY_SIZE limita su tamaño
+// (bytes) y al superarlo se responde 413
+func SignatureMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		golog.Log(r.Context(), "==================> SignatureMiddleware called")
+
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, int64(goenvars.GetEnvInt("SIGNATURE_MAX_BODY_SIZE", 1<<20)))
+		}
+
+		if err := verifySignature(r, route); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				golog.Warning(r.Context(), "Signed request body too large:", maxBytesErr.Limit)
+				golog.Log(r.Context(), "==================> SignatureMiddleware END")
+				errorResponse(w, "Request entity too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			golog.Warning(r.Context(), "Invalid request signature:", err.Error())
+			golog.Log(r.Context(), "==================> SignatureMiddleware END")
+			errorResponse(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		golog.Log(r.Context(), "==================> SignatureMiddleware END")
+		next(w, r)
+	}
+}
+
+func verifySignature(r *http.Request, route definitions.Route) error {
+	secrets, ok := getRouteParamList(route, "signature_secrets")
+	if !ok {
+		secrets = splitList(goenvars.GetEnv("SIGNATURE_SECRETS", ""))
+	}
+
+	if len(secrets) == 0 {
+		return errors.New("no signature secrets configured")
+	}
+
+	signatures := parseSignatures(r.Header.Get(SIGNATURE_HEADER))
+	if len(signatures) == 0 {
+		return errors.New("missing signature")
+	}
+
+	rawTimestamp := r.Header.Get(SIGNATURE_TIMESTAMP_HEADER)
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid signature timestamp")
+	}
+
+	tolerance := time.Duration(goenvars.GetEnvInt("SIGNATURE_TOLERANCE", 300)) * time.Second
+	signedAt := time.Unix(timestamp, 0)
+	if time.Since(signedAt) > tolerance || time.Until(signedAt) > tolerance {
+		return errors.New("stale signature timestamp")
+	}
+
+	nonce := r.Header.Get(SIGNATURE_NONCE_HEADER)
+	if nonce == "" && goenvars.GetEnvBool("SIGNATURE_REQUIRE_NONCE", true) {
+		return errors.New("missing signature nonce")
+	}
+
+	signedHeaders := parseSignedHeaders(r.Header.Get(SIGNATURE_HEADERS_HEADER))
+	if required, ok := getRouteParamList(route, "signature_headers"); ok {
+		for _, header := range required {
+			if !containsString(signedHeaders, strings.ToLower(header)) {
+				return errors.New("header not signed: " + header)
+			}
+		}
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	canonical := canonicalRequest(r, signedHeaders, rawTimestamp, nonce, body)
+
+	valid := false
+	for _, secret := range secrets {
+		expected := computeSignature(secret, canonical)
+		for _, signature := range signatures {
+			if hmac.Equal([]byte(expected), []byte(signature)) {
+				valid = true
+			}
+		}
+	}
+
+	if !valid {
+		return errors.New("signature mismatch")
+	}
+
+	// el nonce se registra solo cuando la firma es válida, así un atacante no puede
+	// "quemar" nonces legítimos enviando firmas falsas
+	if nonce != "" && getSignatureNonceStore().Seen(nonce, signedAt.Add(tolerance)) {
+		return errors.New("replayed nonce")
+	}
+
+	return nil
+}
+
+// SignRequest firma una petición saliente con el secreto indicado, incluyendo los headers
+// enviados en la lista, para que pueda ser validada por SignatureMiddleware
+func SignRequest(r *http.Request, secret string, headers []string) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	signedHeaders := make([]string, 0, len(headers))
+	for _, header := range headers {
+		signedHeaders = append(signedHeaders, strings.ToLower(strings.TrimSpace(header)))
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := helper.GenerateUuid()
+
+	r.Header.Set(SIGNATURE_TIMESTAMP_HEADER, timestamp)
+	r.Header.Set(SIGNATURE_NONCE_HEADER, nonce)
+	r.Header.Set(SIGNATURE_HEADERS_HEADER, strings.Join(signedHeaders, ";"))
+	r.Header.Set(SIGNATURE_HEADER, SIGNATURE_VERSION+"="+computeSignature(secret, canonicalRequest(r, signedHeaders, timestamp, nonce, body)))
+
+	return nil
+}
+
+// canonicalRequest genera el texto que se firma:
+// método, path con query, headers firmados, lista de headers, timestamp, nonce y hash del body
+func canonicalRequest(r *http.Request, signedHeaders []string, timestamp string, nonce string, body []byte) string {
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	var canonical strings.Builder
+	canonical.WriteString(strings.ToUpper(r.Method) + "\n")
+	canonical.WriteString(path + "\n")
+
+	for _, header := range signedHeaders {
+		value := r.Header.Get(header)
+		if header == "host" {
+			value = r.Host
+		}
+		canonical.WriteString(header + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	bodyHash := sha256.Sum256(body)
+
+	canonical.WriteString(strings.Join(signedHeaders, ";") + "\n")
+	canonical.WriteString(timestamp + "\n")
+	canonical.WriteString(nonce + "\n")
+	canonical.WriteString(hex.EncodeToString(bodyHash[:]))
+
+	return canonical.String()
+}
+
+func computeSignature(secret string, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseSignatures extrae las firmas del header, el formato es "v1=<hex>,v1=<hex>"
+// para permitir que el emisor firme con varios secretos durante una rotación
+func parseSignatures(header string) []string {
+	signatures := []string{}
+	for _, part := range splitList(header) {
+		version, signature, found := strings.Cut(part, "=")
+		if found && strings.TrimSpace(version) == SIGNATURE_VERSION {
+			signatures = append(signatures, strings.TrimSpace(signature))
+		}
+	}
+
+	return signatures
+}
+
+func parseSignedHeaders(header string) []string {
+	headers := []string{}
+	for _, item := range strings.Split(header, ";") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			headers = append(headers, item)
+		}
+	}
+
+	return headers
+}
+
+// readBody lee el body completo de la petición y lo restaura para los siguientes handlers
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return []byte{}, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	return body, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
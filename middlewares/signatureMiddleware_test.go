@@ -0,0 +1,199 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+const testSignatureSecret = "test-secret"
+
+func signatureRoute() definitions.Route {
+	return definitions.Route{
+		Pattern: "/webhook",
+		MiddlewareParams: &map[string]interface{}{
+			"signature_secrets": []string{"old-secret", testSignatureSecret},
+			"signature_headers": []string{"content-type"},
+		},
+	}
+}
+
+func signedRequest(t *testing.T, body string) *http.Request {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodPost, "/webhook?event=created", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	if err := SignRequest(r, testSignatureSecret, []string{"Content-Type"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+// serveSigned ejecuta SignatureMiddleware y retorna el status y el body que recibió el handler
+func serveSigned(r *http.Request) (int, string) {
+	var received string
+	handler := SignatureMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}, signatureRoute(), nil)
+
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+
+	return rec.Code, received
+}
+
+// resign reemplaza el timestamp y vuelve a calcular la firma con el mismo nonce
+func resign(t *testing.T, r *http.Request, signedAt time.Time, body string) {
+	t.Helper()
+
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	signedHeaders := parseSignedHeaders(r.Header.Get(SIGNATURE_HEADERS_HEADER))
+	canonical := canonicalRequest(r, signedHeaders, timestamp, r.Header.Get(SIGNATURE_NONCE_HEADER), []byte(body))
+
+	r.Header.Set(SIGNATURE_TIMESTAMP_HEADER, timestamp)
+	r.Header.Set(SIGNATURE_HEADER, SIGNATURE_VERSION+"="+computeSignature(testSignatureSecret, canonical))
+}
+
+func TestSignatureValid(t *testing.T) {
+	status, received := serveSigned(signedRequest(t, `{"id":1}`))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	if received != `{"id":1}` {
+		t.Fatalf("handler must receive the original body, got %q", received)
+	}
+}
+
+func TestSignatureTampered(t *testing.T) {
+	tamperedBody := signedRequest(t, `{"amount":1}`)
+	tamperedBody.Body = io.NopCloser(strings.NewReader(`{"amount":1000}`))
+
+	tamperedQuery := signedRequest(t, `{}`)
+	tamperedQuery.URL.RawQuery = "event=deleted"
+
+	tamperedHeader := signedRequest(t, `{}`)
+	tamperedHeader.Header.Set("Content-Type", "text/plain")
+
+	unsignedHeader := signedRequest(t, `{}`)
+	unsignedHeader.Header.Set(SIGNATURE_HEADERS_HEADER, "")
+
+	wrongSecret := signedRequest(t, `{}`)
+	wrongSecret.Header.Set(SIGNATURE_HEADER, SIGNATURE_VERSION+"="+computeSignature("wrong", "canonical"))
+
+	missing := signedRequest(t, `{}`)
+	missing.Header.Del(SIGNATURE_HEADER)
+
+	cases := map[string]*http.Request{
+		"body":            tamperedBody,
+		"query":           tamperedQuery,
+		"signed header":   tamperedHeader,
+		"required header": unsignedHeader,
+		"wrong secret":    wrongSecret,
+		"missing":         missing,
+	}
+
+	for name, r := range cases {
+		if status, _ := serveSigned(r); status != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d", name, status)
+		}
+	}
+}
+
+func TestSignatureClockSkew(t *testing.T) {
+	t.Setenv("SIGNATURE_TOLERANCE", "60")
+
+	cases := []struct {
+		name   string
+		offset time.Duration
+		status int
+	}{
+		{"within tolerance", -30 * time.Second, http.StatusOK},
+		{"future within tolerance", 30 * time.Second, http.StatusOK},
+		{"stale", -2 * time.Minute, http.StatusUnauthorized},
+		{"future", 2 * time.Minute, http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		r := signedRequest(t, `{}`)
+		resign(t, r, time.Now().Add(c.offset), `{}`)
+
+		if status, _ := serveSigned(r); status != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.status, status)
+		}
+	}
+}
+
+func TestSignatureNonceReplay(t *testing.T) {
+	SetSignatureNonceStore(NewMemoryNonceStore())
+	t.Cleanup(func() { SetSignatureNonceStore(nil) })
+
+	original := signedRequest(t, `{"id":1}`)
+	replay := httptest.NewRequest(http.MethodPost, "/webhook?event=created", strings.NewReader(`{"id":1}`))
+	replay.Header = original.Header.Clone()
+
+	if status, _ := serveSigned(original); status != http.StatusOK {
+		t.Fatalf("expected 200 for the first request, got %d", status)
+	}
+
+	if status, _ := serveSigned(replay); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for the replayed request, got %d", status)
+	}
+
+	// una firma inválida no registra el nonce
+	forged := signedRequest(t, `{}`)
+	legit := httptest.NewRequest(http.MethodPost, "/webhook?event=created", strings.NewReader(`{}`))
+	legit.Header = forged.Header.Clone()
+	forged.Header.Set(SIGNATURE_HEADER, SIGNATURE_VERSION+"=00")
+
+	serveSigned(forged)
+	if status, _ := serveSigned(legit); status != http.StatusOK {
+		t.Fatalf("a forged signature must not burn the nonce, got %d", status)
+	}
+
+	missingNonce := signedRequest(t, `{}`)
+	missingNonce.Header.Del(SIGNATURE_NONCE_HEADER)
+	if status, _ := serveSigned(missingNonce); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without nonce, got %d", status)
+	}
+}
+
+func TestSignatureBodyLimit(t *testing.T) {
+	t.Setenv("SIGNATURE_MAX_BODY_SIZE", "16")
+
+	if status, _ := serveSigned(signedRequest(t, strings.Repeat("x", 17))); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", status)
+	}
+
+	if status, _ := serveSigned(signedRequest(t, strings.Repeat("x", 16))); status != http.StatusOK {
+		t.Fatalf("expected 200 within the limit, got %d", status)
+	}
+}
+
+func TestSetSignatureNonceStoreConcurrent(t *testing.T) {
+	t.Cleanup(func() { SetSignatureNonceStore(nil) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetSignatureNonceStore(NewMemoryNonceStore())
+		}()
+		go func() {
+			defer wg.Done()
+			getSignatureNonceStore().Seen("nonce", time.Now().Add(time.Minute))
+		}()
+	}
+	wg.Wait()
+}
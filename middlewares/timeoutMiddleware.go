@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// TimeoutMiddleware limita el tiempo de ejecución del handler a Route.Timeout. El handler recibe
+// un contexto con deadline; si el tiempo se agota se responde 504 (o 503 si la petición fue
+// cancelada) con el error estándar y las escrituras posteriores del handler se descartan.
+//
+// La respuesta del handler se mantiene en memoria hasta que termina, por lo que no debe usarse
+// en rutas de streaming.
+func TimeoutMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if route.Timeout <= 0 {
+			next(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), route.Timeout)
+		defer cancel()
+
+		tw := &timeoutWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicChan := make(chan any, 1)
+
+		go func() {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+
+				// después del timeout nadie lee panicChan, el panic solo se registra
+				tw.mu.Lock()
+				timedOut := tw.timedOut
+				tw.mu.Unlock()
+
+				if timedOut {
+					logLatePanic(r, p, debug.Stack())
+					return
+				}
+
+				panicChan <- p
+			}()
+
+			next(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			// se vuelve a lanzar el panic en la goroutine de la petición para que lo capture RecoveryMiddleware
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			dst := w.Header()
+			for key, values := range tw.header {
+				dst[key] = values
+			}
+
+			if tw.status == 0 {
+				tw.status = http.StatusOK
+			}
+
+			w.WriteHeader(tw.status)
+			w.Write(tw.body.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+
+			// el panic pudo enviarse justo antes de marcar el timeout
+			select {
+			case p := <-panicChan:
+				logLatePanic(r, p, nil)
+			default:
+			}
+
+			statusCode := http.StatusServiceUnavailable
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				statusCode = http.StatusGatewayTimeout
+			}
+
+			golog.Warning(r.Context(), "Request timeout exceeded:", route.Timeout.String(), "for route:", r.URL.Path)
+			errorResponse(w, "Request timeout", statusCode)
+		}
+	}
+}
+
+// logLatePanic registra un panic del handler ocurrido cuando ya se respondió el timeout
+func logLatePanic(r *http.Request, p any, stack []byte) {
+	golog.Error(r.Context(), "Panic after request timeout:", fmt.Sprint(p), "Route:", r.Method, r.URL.Path, string(stack))
+}
+
+// timeoutWriter guarda la respuesta del handler hasta que termina, después del timeout
+// descarta cualquier escritura
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+
+	return tw.body.Write(b)
+}
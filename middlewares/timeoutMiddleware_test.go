@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+func TestTimeoutPanic(t *testing.T) {
+	cases := []struct {
+		name   string
+		delay  time.Duration
+		status int
+	}{
+		{"before timeout", 0, http.StatusInternalServerError},
+		{"after timeout", 50 * time.Millisecond, http.StatusGatewayTimeout},
+	}
+
+	for _, c := range cases {
+		finished := make(chan struct{})
+		handler := RecoveryMiddleware(TimeoutMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			defer close(finished)
+			time.Sleep(c.delay)
+			panic("handler failed")
+		}, definitions.Route{Timeout: 20 * time.Millisecond}, nil), definitions.Route{}, nil)
+
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.status, rec.Code)
+		}
+
+		select {
+		case <-finished:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: the handler did not finish", c.name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/helper/http/wr"
+	"github.com/Nemutagk/goroutes/tracing"
+)
+
+// TracingMiddleware crea el span de servidor de la petición continuando la traza recibida en
+// traceparent/tracestate, el span queda en r.Context() para los spans hijos
+func TracingMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.StartSpan(ctx, r.Method+" "+route.Pattern, tracing.SpanKindServer)
+		defer span.End()
+
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("http.route", route.Pattern)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("client.address", r.RemoteAddr)
+
+		wrEnv := wr.Acquire(w)
+		defer wr.Release(wrEnv)
+		statusCode := http.StatusInternalServerError
+
+		defer func() {
+			span.SetAttribute("http.response.status_code", statusCode)
+			if statusCode >= http.StatusInternalServerError {
+				span.SetError(&httpStatusError{statusCode: statusCode})
+			}
+		}()
+
+		next(wrEnv.Writer(), r.WithContext(ctx))
+		statusCode = wrEnv.GetStatus()
+	}
+}
+
+type httpStatusError struct {
+	statusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return http.StatusText(e.statusCode)
+}
@@ -0,0 +1,35 @@
+package goroutes
+
+import (
+	"net/http"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// mountRoute convierte un Mount en una ruta sin método cuyo patrón termina en "/" para que
+// ServeMux le entregue todo el subárbol del prefijo
+func mountRoute(mount definitions.Mount, parentPath string) definitions.Route {
+	prefix := preparePath(mount.Prefix, parentPath)
+
+	pattern := prefix
+	handler := mount.Handler
+	if prefix != "/" {
+		pattern = prefix + "/"
+		handler = http.StripPrefix(prefix, handler)
+	}
+
+	return definitions.Route{
+		Path:               mount.Prefix,
+		Pattern:            pattern,
+		Action:             handler.ServeHTTP,
+		Middlewares:        mount.Middlewares,
+		MiddlewareParams:   mount.MiddlewareParams,
+		ExcludeMiddlewares: mount.ExcludeMiddlewares,
+		Timeout:            mount.Timeout,
+	}
+}
+
+// isMount indica si la ruta viene de un Mount (sin método y sin grupo de métodos)
+func isMount(route definitions.Route) bool {
+	return route.Method == "" && route.Group == nil && route.Action != nil
+}
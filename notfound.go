@@ -0,0 +1,123 @@
+package goroutes
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goerrors"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/middlewares"
+)
+
+var (
+	unmatchedMu             sync.RWMutex
+	notFoundHandler         http.HandlerFunc = NotFoundHandler
+	methodNotAllowedHandler http.HandlerFunc = MethodNotAllowedHandler
+)
+
+// SetNotFoundHandler reemplaza la respuesta de las peticiones que no coinciden con ninguna ruta
+func SetNotFoundHandler(handler http.HandlerFunc) {
+	unmatchedMu.Lock()
+	defer unmatchedMu.Unlock()
+
+	notFoundHandler = handler
+}
+
+// SetMethodNotAllowedHandler reemplaza la respuesta cuando la ruta existe pero no para el método
+// de la petición, el header Allow ya viene definido al llamar al handler
+func SetMethodNotAllowedHandler(handler http.HandlerFunc) {
+	unmatchedMu.Lock()
+	defer unmatchedMu.Unlock()
+
+	methodNotAllowedHandler = handler
+}
+
+func getNotFoundHandler() http.HandlerFunc {
+	unmatchedMu.RLock()
+	defer unmatchedMu.RUnlock()
+
+	return notFoundHandler
+}
+
+func getMethodNotAllowedHandler() http.HandlerFunc {
+	unmatchedMu.RLock()
+	defer unmatchedMu.RUnlock()
+
+	return methodNotAllowedHandler
+}
+
+// NotFoundHandler es el 404 por defecto, el formato se negocia con el header Accept
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	respondError(w, r, "Not found", http.StatusNotFound)
+}
+
+// MethodNotAllowedHandler es el 405 por defecto, el formato se negocia con el header Accept
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	respondError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+}
+
+// errorBody tiene los mismos campos que goerrors.GError para que el error se vea igual en JSON
+// y también se pueda representar en XML y texto plano
+type errorBody struct {
+	XMLName xml.Name `json:"-" xml:"error"`
+	Message string   `json:"message" xml:"message"`
+	Status  int      `json:"status" xml:"status"`
+}
+
+func (e errorBody) String() string {
+	return e.Message
+}
+
+// respondError responde el error en el formato aceptado por el cliente, si ninguno aplica se
+// usa el JSON estándar en lugar de responder 406
+func respondError(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
+	if !respond(w, r, errorBody{Message: message, Status: statusCode}, statusCode) {
+		GoErrorResponse(w, *goerrors.NewGError(message, statusCode, nil, nil))
+	}
+}
+
+// NotFoundAction es el catch-all que registra LoadRoutes, con GOROUTES_NOT_FOUND_ENABLED=false
+// la aplicación puede registrarlo donde quiera (ej. server.Handle("/", ...)), también resuelve
+// las rutas de hosts con parámetros
+func NotFoundAction(server *http.ServeMux, dbConnectionsList map[string]db.DbConnection) http.HandlerFunc {
+	return getHostRouter(server).dispatch(unmatchedAction(getNotFoundHandler, loadDefaultMiddlewares(), dbConnectionsList))
+}
+
+// unmatchedAction ejecuta el handler de 404/405 con los middlewares por defecto para que las
+// peticiones sin ruta también queden en los logs de acceso y métricas
+func unmatchedAction(handler func() http.HandlerFunc, defaultMiddlewares []definitions.Middleware, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	mws := []definitions.Middleware{}
+	for _, mw := range defaultMiddlewares {
+		// sin conexiones AccessMiddleware respondería 500 en lugar del 404/405
+		if dbListConn == nil && containsMiddleware([]definitions.Middleware{middlewares.AccessMiddleware}, mw) {
+			continue
+		}
+		mws = append(mws, mw)
+	}
+
+	return buildAction(definitions.Route{
+		Action: func(w http.ResponseWriter, r *http.Request) {
+			handler()(w, r)
+		},
+		Middlewares: &mws,
+	}, dbListConn)
+}
+
+// allowedMethods genera el valor del header Allow de una ruta
+func allowedMethods(route definitions.Route) string {
+	methods := []string{http.MethodOptions}
+	if len(route.Group) == 0 {
+		methods = append(methods, route.Method)
+	}
+
+	for method := range route.Group {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return strings.Join(methods, ", ")
+}
@@ -0,0 +1,169 @@
+package goroutes
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// mergeRoutes agrega los métodos de route a la ruta existente con el mismo path, si un método
+// ya existe se conserva el del primer grupo. Los Mount y las rutas con versiones no se combinan
+func mergeRoutes(path string, existing definitions.Route, route definitions.Route) (definitions.Route, bool) {
+	if isMount(existing) || isMount(route) || len(existing.Versions) > 0 || len(route.Versions) > 0 {
+		return existing, false
+	}
+
+	methods := routeMethods(existing)
+	for method, methodRoute := range routeMethods(route) {
+		if _, exists := methods[method]; exists {
+			golog.Warning(context.Background(), "Route already exists, keeping the first definition:", path, "Method:", method)
+			continue
+		}
+
+		methods[method] = methodRoute
+	}
+
+	return groupRoutes(methods), true
+}
+
+// routeMethods separa la ruta por método, las rutas con un solo método no tienen Group
+func routeMethods(route definitions.Route) map[string]definitions.Route {
+	methods := map[string]definitions.Route{}
+	if len(route.Group) == 0 {
+		methods[route.Method] = route
+		return methods
+	}
+
+	for method, methodRoute := range route.Group {
+		methodRoute.Group = nil
+		methods[method] = methodRoute
+	}
+
+	return methods
+}
+
+// groupRoutes arma la ruta que se registra en el mux a partir de sus métodos
+func groupRoutes(methods map[string]definitions.Route) definitions.Route {
+	keys := slices.Sorted(maps.Keys(methods))
+	route := methods[keys[0]]
+	if len(keys) > 1 {
+		route.Group = methods
+	}
+
+	return route
+}
+
+// applyOverrides aplica los overrides a las rutas ya cargadas, los que no coinciden con ninguna
+// ruta se registran como error para detectar nombres o patrones obsoletos
+func applyOverrides(routeList map[string]definitions.Route, overrides []definitions.RouteOverride) {
+	for _, override := range overrides {
+		method, path := overridePattern(override.Pattern)
+		matched := false
+
+		for key, route := range routeList {
+			// el path sin versión de un VersionedGroup se resuelve con las rutas de cada versión
+			if len(route.Versions) > 0 {
+				continue
+			}
+
+			if path != "" && strings.TrimSuffix(key, "/") != strings.TrimSuffix(path, "/") {
+				continue
+			}
+
+			methods := routeMethods(route)
+			changed := false
+			for routeMethod, methodRoute := range methods {
+				if method != "" && routeMethod != method {
+					continue
+				}
+
+				if override.Name != "" && methodRoute.Name != override.Name {
+					continue
+				}
+
+				matched = true
+				changed = true
+				if override.Disabled {
+					delete(methods, routeMethod)
+					continue
+				}
+
+				methods[routeMethod] = overrideRoute(methodRoute, override)
+			}
+
+			if !changed {
+				continue
+			}
+
+			if len(methods) == 0 {
+				delete(routeList, key)
+				continue
+			}
+
+			routeList[key] = groupRoutes(methods)
+		}
+
+		if !matched {
+			golog.Error(context.Background(), "Route override without matching route:", override.Name, override.Pattern)
+		}
+	}
+}
+
+// overridePattern separa el método del patrón ("GET /users" -> "GET", "/users")
+func overridePattern(pattern string) (string, string) {
+	pattern = strings.TrimSpace(pattern)
+	if method, path, found := strings.Cut(pattern, " "); found {
+		return strings.ToUpper(method), strings.TrimSpace(path)
+	}
+
+	return "", pattern
+}
+
+func overrideRoute(route definitions.Route, override definitions.RouteOverride) definitions.Route {
+	if override.Auth != nil {
+		route.Auth = override.Auth
+	}
+
+	if override.Timeout != 0 {
+		route.Timeout = override.Timeout
+	}
+
+	if override.MiddlewareParams != nil {
+		params := map[string]interface{}{}
+		if route.MiddlewareParams != nil {
+			maps.Copy(params, *route.MiddlewareParams)
+		}
+		maps.Copy(params, *override.MiddlewareParams)
+		route.MiddlewareParams = &params
+	}
+
+	// la cadena de la ruta ya incluye los middlewares del grupo y los por defecto
+	if override.Middlewares != nil || override.ExcludeMiddlewares != nil {
+		mws := []definitions.Middleware{}
+		if route.Middlewares != nil {
+			mws = append(mws, *route.Middlewares...)
+		}
+
+		if override.Middlewares != nil {
+			for _, md := range *override.Middlewares {
+				if !containsMiddleware(mws, md) {
+					mws = append(mws, md)
+				}
+			}
+		}
+
+		if override.ExcludeMiddlewares != nil {
+			mws = slices.DeleteFunc(mws, func(md definitions.Middleware) bool {
+				return containsMiddleware(*override.ExcludeMiddlewares, md)
+			})
+		}
+
+		route.Middlewares = &mws
+	}
+
+	return route
+}
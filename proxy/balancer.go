@@ -0,0 +1,216 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type upstream struct {
+	target *url.URL
+	active atomic.Int64
+
+	mu           sync.Mutex
+	fails        int
+	ejectedUntil time.Time
+}
+
+func (u *upstream) available(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return !now.Before(u.ejectedUntil)
+}
+
+// balancer elige el upstream de cada intento y lleva el registro pasivo de fallos
+type balancer struct {
+	upstreams        []*upstream
+	leastConnections bool
+	maxFails         int
+	failTimeout      time.Duration
+	next             atomic.Uint64
+}
+
+// pick elige un upstream disponible que no se haya intentado en la petición, si todos fueron
+// expulsados se usan de todas formas para no rechazar el tráfico
+func (b *balancer) pick(tried map[*upstream]bool) *upstream {
+	now := time.Now()
+
+	candidates := make([]*upstream, 0, len(b.upstreams))
+	for _, u := range b.upstreams {
+		if !tried[u] && u.available(now) {
+			candidates = append(candidates, u)
+		}
+	}
+
+	if len(candidates) == 0 {
+		for _, u := range b.upstreams {
+			if !tried[u] {
+				candidates = append(candidates, u)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	start := int(b.next.Add(1)-1) % len(candidates)
+	if !b.leastConnections {
+		return candidates[start]
+	}
+
+	// empieza en la posición del round robin para repartir los empates
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		candidate := candidates[(start+i)%len(candidates)]
+		if candidate.active.Load() < best.active.Load() {
+			best = candidate
+		}
+	}
+
+	return best
+}
+
+func (b *balancer) success(u *upstream) {
+	u.mu.Lock()
+	u.fails = 0
+	u.mu.Unlock()
+}
+
+func (b *balancer) failure(u *upstream) {
+	if b.maxFails <= 0 {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.fails++
+	if u.fails >= b.maxFails {
+		u.fails = 0
+		u.ejectedUntil = time.Now().Add(b.failTimeout)
+	}
+}
+
+// retryTransport envía cada intento a un upstream del balanceo
+type retryTransport struct {
+	transport http.RoundTripper
+	balancer  *balancer
+	retries   int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tried := map[*upstream]bool{}
+	attempts := 1
+	if retryable(req) {
+		attempts += t.retries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		u := t.balancer.pick(tried)
+		if u == nil {
+			break
+		}
+		tried[u] = true
+
+		outReq := req.Clone(req.Context())
+		outReq.URL.Scheme = u.target.Scheme
+		outReq.URL.Host = u.target.Host
+		outReq.URL.Path = joinPath(u.target.Path, req.URL.Path)
+		outReq.URL.RawPath = joinPath(u.target.EscapedPath(), req.URL.EscapedPath())
+		if u.target.RawQuery != "" && req.URL.RawQuery != "" {
+			outReq.URL.RawQuery = u.target.RawQuery + "&" + req.URL.RawQuery
+		} else if u.target.RawQuery != "" {
+			outReq.URL.RawQuery = u.target.RawQuery
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			outReq.Body = body
+		}
+
+		u.active.Add(1)
+		res, err := t.transport.RoundTrip(outReq)
+		if err != nil {
+			u.active.Add(-1)
+
+			// la cancelación del cliente no es un fallo del upstream
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+
+			t.balancer.failure(u)
+			lastErr = err
+			continue
+		}
+
+		if !gatewayError(res.StatusCode) {
+			t.balancer.success(u)
+
+			// ReverseProxy necesita el body original (io.ReadWriteCloser) para los upgrades
+			if res.StatusCode == http.StatusSwitchingProtocols {
+				u.active.Add(-1)
+				return res, nil
+			}
+
+			res.Body = &countedBody{ReadCloser: res.Body, upstream: u}
+			return res, nil
+		}
+
+		t.balancer.failure(u)
+		if attempt == attempts-1 || len(tried) == len(t.balancer.upstreams) {
+			res.Body = &countedBody{ReadCloser: res.Body, upstream: u}
+			return res, nil
+		}
+
+		io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+		res.Body.Close()
+		u.active.Add(-1)
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("proxy: no upstream available")
+	}
+
+	return nil, lastErr
+}
+
+// retryable indica si la petición se puede repetir: método idempotente y body que se puede leer
+// otra vez
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+	default:
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func gatewayError(statusCode int) bool {
+	return statusCode == http.StatusBadGateway || statusCode == http.StatusServiceUnavailable || statusCode == http.StatusGatewayTimeout
+}
+
+// countedBody mantiene la conexión como activa hasta que se termina de copiar la respuesta
+type countedBody struct {
+	io.ReadCloser
+	upstream *upstream
+	once     sync.Once
+}
+
+func (b *countedBody) Close() error {
+	b.once.Do(func() {
+		b.upstream.active.Add(-1)
+	})
+
+	return b.ReadCloser.Close()
+}
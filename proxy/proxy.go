@@ -0,0 +1,227 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/goerrors"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+)
+
+// Config define los upstreams y el comportamiento del proxy
+type Config struct {
+	// Upstreams son las URLs base de los servicios (ej. http://users:8080/v1), el path de la
+	// petición se agrega al path de la URL
+	Upstreams []string
+	// Balancer elige el upstream de cada intento: RoundRobin (default) o LeastConnections
+	Balancer string
+	// Rewrite modifica el path (sin el prefijo del Mount) antes de enviarlo al upstream, también se
+	// aplica al path escapado para conservar caracteres como %2F
+	Rewrite func(path string) string
+	// Timeout es el tiempo máximo para recibir los headers del upstream, DialTimeout el de conexión
+	Timeout     time.Duration
+	DialTimeout time.Duration
+	// Retries es el número de reintentos con otro upstream para los métodos idempotentes cuando
+	// la conexión falla o el upstream responde 502, 503 o 504
+	Retries int
+	// MaxFails fallos consecutivos sacan al upstream del balanceo durante FailTimeout, 0 lo desactiva
+	MaxFails    int
+	FailTimeout time.Duration
+	// RequestIDHeader y PrincipalHeader son los headers con el request ID y el principal
+	// autenticado (string o JSON) que se envían al upstream, vacío no los envía
+	RequestIDHeader string
+	PrincipalHeader string
+	// Transport reemplaza el transporte HTTP (ej. en pruebas), si se define se ignoran los timeouts
+	Transport http.RoundTripper
+}
+
+// LoadConfigFromEnv genera la configuración a partir de GOROUTES_PROXY_*, los upstreams se
+// definen en cada ruta
+func LoadConfigFromEnv(upstreams ...string) Config {
+	return Config{
+		Upstreams:       upstreams,
+		Balancer:        goenvars.GetEnv("GOROUTES_PROXY_BALANCER", RoundRobin),
+		Timeout:         time.Duration(goenvars.GetEnvInt("GOROUTES_PROXY_TIMEOUT", 30)) * time.Second,
+		DialTimeout:     time.Duration(goenvars.GetEnvInt("GOROUTES_PROXY_DIAL_TIMEOUT", 5)) * time.Second,
+		Retries:         goenvars.GetEnvInt("GOROUTES_PROXY_RETRIES", 1),
+		MaxFails:        goenvars.GetEnvInt("GOROUTES_PROXY_MAX_FAILS", 3),
+		FailTimeout:     time.Duration(goenvars.GetEnvInt("GOROUTES_PROXY_FAIL_TIMEOUT", 30)) * time.Second,
+		RequestIDHeader: goenvars.GetEnv("GOROUTES_PROXY_REQUEST_ID_HEADER", "X-Request-Id"),
+		PrincipalHeader: goenvars.GetEnv("GOROUTES_PROXY_PRINCIPAL_HEADER", "X-Authenticated-Principal"),
+	}
+}
+
+// Proxy reenvía las peticiones a los upstreams con httputil.ReverseProxy
+type Proxy struct {
+	config   Config
+	balancer *balancer
+	reverse  *httputil.ReverseProxy
+}
+
+// New valida los upstreams y genera el proxy
+func New(config Config) (*Proxy, error) {
+	if len(config.Upstreams) == 0 {
+		return nil, errors.New("proxy: at least one upstream is required")
+	}
+
+	if config.Balancer == "" {
+		config.Balancer = RoundRobin
+	}
+
+	if config.Balancer != RoundRobin && config.Balancer != LeastConnections {
+		return nil, errors.New("proxy: unknown balancer " + config.Balancer)
+	}
+
+	upstreams := make([]*upstream, 0, len(config.Upstreams))
+	for _, rawUrl := range config.Upstreams {
+		target, err := url.Parse(rawUrl)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return nil, errors.New("proxy: invalid upstream " + rawUrl)
+		}
+
+		upstreams = append(upstreams, &upstream{target: target})
+	}
+
+	transport := config.Transport
+	if transport == nil {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.DialContext = (&net.Dialer{Timeout: config.DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+		base.ResponseHeaderTimeout = config.Timeout
+		transport = base
+	}
+
+	p := &Proxy{
+		config:   config,
+		balancer: &balancer{upstreams: upstreams, leastConnections: config.Balancer == LeastConnections, maxFails: config.MaxFails, failTimeout: config.FailTimeout},
+	}
+
+	p.reverse = &httputil.ReverseProxy{
+		Rewrite:      p.rewrite,
+		Transport:    &retryTransport{transport: transport, balancer: p.balancer, retries: config.Retries},
+		ErrorHandler: errorHandler,
+	}
+
+	return p, nil
+}
+
+// Mount genera un definitions.Mount que reenvía todo lo que está bajo prefix después de los
+// middlewares del grupo (auth, logs de acceso). El Timeout del grupo se desactiva porque el proxy
+// tiene sus propios timeouts y no debe guardar la respuesta en memoria
+func Mount(prefix string, config Config) (definitions.Mount, error) {
+	p, err := New(config)
+	if err != nil {
+		return definitions.Mount{}, err
+	}
+
+	return definitions.Mount{
+		Prefix:  prefix,
+		Handler: p,
+		Timeout: -1,
+	}, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.reverse.ServeHTTP(w, r)
+}
+
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	pr.SetXForwarded()
+
+	path := pr.In.URL.Path
+	rawPath := pr.In.URL.RawPath
+	if p.config.Rewrite != nil {
+		path = p.config.Rewrite(path)
+		// si el path escapado reescrito no corresponde al path, url.URL usa el path decodificado
+		if rawPath != "" {
+			rawPath = p.config.Rewrite(rawPath)
+		}
+	}
+
+	// el upstream se elige en cada intento del transporte, aquí solo queda el path relativo
+	pr.Out.URL = &url.URL{Path: path, RawPath: rawPath, RawQuery: pr.In.URL.RawQuery}
+	pr.Out.Host = ""
+
+	header := pr.Out.Header
+	ctx := pr.In.Context()
+
+	if p.config.RequestIDHeader != "" {
+		if requestId, ok := ctx.Value(definitions.RequestIDKey).(string); ok && requestId != "" {
+			header.Set(p.config.RequestIDHeader, requestId)
+		}
+	}
+
+	if p.config.PrincipalHeader != "" {
+		// el cliente no puede enviar su propio principal
+		header.Del(p.config.PrincipalHeader)
+
+		if principal := principalValue(ctx.Value(definitions.AuthKey)); principal != "" {
+			header.Set(p.config.PrincipalHeader, principal)
+		}
+	}
+}
+
+func principalValue(principal any) string {
+	switch value := principal.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	}
+
+	data, err := json.Marshal(principal)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+func errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) {
+		// el cliente cerró la conexión, no hay a quién responder
+		return
+	}
+
+	golog.Error(r.Context(), "Proxy error:", err.Error())
+
+	statusCode := http.StatusBadGateway
+	message := "Bad gateway"
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		statusCode = http.StatusGatewayTimeout
+		message = "Gateway timeout"
+	}
+
+	gErr := goerrors.NewGError(message, statusCode, nil, nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(gErr.GetStatusCode())
+	w.Write([]byte(gErr.ToJson()))
+}
+
+// joinPath agrega el path de la petición al path base del upstream, se usa igual con los paths
+// decodificados y con los escapados
+func joinPath(base, path string) string {
+	if base == "" || base == "/" {
+		if path == "" {
+			return "/"
+		}
+		return path
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
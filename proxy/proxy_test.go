@@ -0,0 +1,261 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "goroutes-proxy")
+	if err != nil {
+		panic(err)
+	}
+
+	golog.Init(map[string]db.DbConnection{}, golog.WithFileDriver(filepath.Join(dir, "proxy.log"), false))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newUpstream(t *testing.T, name string, status int, hits *atomic.Int64) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			hits.Add(1)
+		}
+
+		w.WriteHeader(status)
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newProxy(t *testing.T, config Config) *Proxy {
+	t.Helper()
+
+	p, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func serve(p http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestRoundRobin(t *testing.T) {
+	a := newUpstream(t, "a", http.StatusOK, nil)
+	b := newUpstream(t, "b", http.StatusOK, nil)
+	p := newProxy(t, Config{Upstreams: []string{a.URL, b.URL}})
+
+	got := []string{}
+	for i := 0; i < 4; i++ {
+		rec := serve(p, httptest.NewRequest(http.MethodGet, "/", nil))
+		got = append(got, rec.Body.String())
+	}
+
+	if got[0] == got[1] || got[0] != got[2] || got[1] != got[3] {
+		t.Fatalf("expected alternating upstreams, got %v", got)
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("slow"))
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	fast := newUpstream(t, "fast", http.StatusOK, nil)
+	p := newProxy(t, Config{Upstreams: []string{slow.URL, fast.URL}, Balancer: LeastConnections})
+
+	// la primera petición queda activa en slow
+	go serve(p, httptest.NewRequest(http.MethodGet, "/", nil))
+	deadline := time.Now().Add(time.Second)
+	for p.balancer.upstreams[0].active.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("request never reached the slow upstream")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		if rec := serve(p, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Body.String() != "fast" {
+			t.Fatalf("expected fast upstream, got %q", rec.Body.String())
+		}
+	}
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	var failHits, okHits atomic.Int64
+	failing := newUpstream(t, "failing", http.StatusServiceUnavailable, &failHits)
+	ok := newUpstream(t, "ok", http.StatusOK, &okHits)
+	p := newProxy(t, Config{Upstreams: []string{failing.URL, ok.URL}, Retries: 1})
+
+	rec := serve(p, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Fatalf("expected retry on the healthy upstream, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	if failHits.Load() != 1 || okHits.Load() != 1 {
+		t.Fatalf("expected one hit per upstream, got failing=%d ok=%d", failHits.Load(), okHits.Load())
+	}
+}
+
+func TestNoRetryForPost(t *testing.T) {
+	var failHits, okHits atomic.Int64
+	failing := newUpstream(t, "failing", http.StatusServiceUnavailable, &failHits)
+	ok := newUpstream(t, "ok", http.StatusOK, &okHits)
+	p := newProxy(t, Config{Upstreams: []string{failing.URL, ok.URL}, Retries: 1})
+
+	rec := serve(p, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected upstream 503 without retry, got %d", rec.Code)
+	}
+
+	if okHits.Load() != 0 {
+		t.Fatalf("POST must not be retried, ok upstream got %d hits", okHits.Load())
+	}
+}
+
+func TestPassiveEjection(t *testing.T) {
+	var failHits atomic.Int64
+	failing := newUpstream(t, "failing", http.StatusBadGateway, &failHits)
+	ok := newUpstream(t, "ok", http.StatusOK, nil)
+	p := newProxy(t, Config{Upstreams: []string{failing.URL, ok.URL}, Retries: 1, MaxFails: 1, FailTimeout: time.Minute})
+
+	for i := 0; i < 6; i++ {
+		if rec := serve(p, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Body.String() != "ok" {
+			t.Fatalf("request %d: expected ok upstream, got %d %q", i, rec.Code, rec.Body.String())
+		}
+	}
+
+	if failHits.Load() != 1 {
+		t.Fatalf("ejected upstream should receive a single request, got %d", failHits.Load())
+	}
+}
+
+func TestUnavailableUpstream(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	p := newProxy(t, Config{Upstreams: []string{down.URL}})
+	if rec := serve(p, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", rec.Code)
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	var got http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	t.Cleanup(upstream.Close)
+
+	p := newProxy(t, Config{
+		Upstreams:       []string{upstream.URL},
+		RequestIDHeader: "X-Request-Id",
+		PrincipalHeader: "X-Authenticated-Principal",
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Authenticated-Principal", "spoofed")
+	ctx := context.WithValue(req.Context(), definitions.RequestIDKey, "req-1")
+	ctx = context.WithValue(ctx, definitions.AuthKey, map[string]any{"sub": "user-1"})
+	serve(p, req.WithContext(ctx))
+
+	if got.Get("X-Request-Id") != "req-1" {
+		t.Fatalf("expected request id header, got %q", got.Get("X-Request-Id"))
+	}
+
+	if principal := got.Get("X-Authenticated-Principal"); principal != `{"sub":"user-1"}` {
+		t.Fatalf("expected principal JSON, got %q", principal)
+	}
+
+	// sin principal en el contexto el header del cliente se descarta
+	serve(p, httptest.NewRequest(http.MethodGet, "/", nil))
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Authenticated-Principal", "spoofed")
+	serve(p, req)
+	if principal := got.Get("X-Authenticated-Principal"); principal != "" {
+		t.Fatalf("spoofed principal reached the upstream: %q", principal)
+	}
+
+	if got.Get("X-Forwarded-For") == "" {
+		t.Fatal("expected X-Forwarded-For header")
+	}
+}
+
+func TestEscapedPath(t *testing.T) {
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+	}))
+	t.Cleanup(upstream.Close)
+
+	cases := []struct {
+		name    string
+		config  Config
+		request string
+		want    string
+	}{
+		{"plain", Config{Upstreams: []string{upstream.URL}}, "/files/a%2Fb", "/files/a%2Fb"},
+		{"base path", Config{Upstreams: []string{upstream.URL + "/base"}}, "/files/a%2Fb", "/base/files/a%2Fb"},
+		{"rewrite", Config{Upstreams: []string{upstream.URL}, Rewrite: func(path string) string { return "/v2" + path }}, "/files/a%2Fb", "/v2/files/a%2Fb"},
+		{"unescaped", Config{Upstreams: []string{upstream.URL + "/base"}}, "/users/1", "/base/users/1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := newProxy(t, c.config)
+			serve(p, httptest.NewRequest(http.MethodGet, c.request, nil))
+
+			if gotPath != c.want {
+				t.Fatalf("expected %q, got %q", c.want, gotPath)
+			}
+		})
+	}
+}
+
+func TestMountStripsPrefix(t *testing.T) {
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(upstream.Close)
+
+	mount, err := Mount("/svc", Config{Upstreams: []string{upstream.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := http.StripPrefix("/svc", mount.Handler)
+	serve(handler, httptest.NewRequest(http.MethodGet, "/svc/files/a%2Fb", nil))
+
+	if gotPath != "/files/a%2Fb" {
+		t.Fatalf("expected escaped path without prefix, got %q", gotPath)
+	}
+}
@@ -0,0 +1,66 @@
+package goroutes
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Nemutagk/goerrors"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/helper/encoding"
+)
+
+// Respond serializa data en el formato preferido por el header Accept (JSON, XML, texto plano,
+// CSV, MessagePack, CBOR o los agregados con encoding.Register). Si un encoder no soporta el valor
+// (ej. CSV con un objeto) se intenta el siguiente aceptado, si ninguno aplica responde 406
+func Respond(w http.ResponseWriter, r *http.Request, data any, statusCode int) {
+	if !respond(w, r, data, statusCode) {
+		GoErrorResponse(w, *goerrors.NewGError("Not acceptable", goerrors.StatusNotAcceptable, nil, nil))
+	}
+}
+
+// respond escribe la respuesta con el primer encoder aceptado que soporte el valor, retorna
+// false sin escribir nada si ninguno aplica
+func respond(w http.ResponseWriter, r *http.Request, data any, statusCode int) bool {
+	if data == nil {
+		data = map[string]any{"message": "No content"}
+	}
+
+	if !varyContains(w.Header(), "Accept") {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	for _, encoder := range encoding.Negotiate(r.Header.Get("Accept")) {
+		var buf bytes.Buffer
+		if err := encoder.Encode(&buf, data); err != nil {
+			if errors.Is(err, encoding.ErrUnsupported) {
+				continue
+			}
+
+			golog.Error(r.Context(), "Error encoding response:", err)
+			GoErrorResponse(w, *goerrors.NewGError("Failed to encode response", goerrors.StatusInternalServerError, nil, goerrors.ConvertError(err)))
+			return true
+		}
+
+		w.Header().Set("Content-Type", encoder.ContentType())
+		w.WriteHeader(statusCode)
+		w.Write(buf.Bytes())
+		return true
+	}
+
+	return false
+}
+
+// varyContains indica si el header Vary ya incluye el header indicado
+func varyContains(header http.Header, name string) bool {
+	for _, value := range header.Values("Vary") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
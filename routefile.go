@@ -0,0 +1,326 @@
+package goroutes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/middlewares"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	registryMu sync.RWMutex
+	handlers   = map[string]http.HandlerFunc{}
+
+	// los middlewares incluidos se pueden usar por nombre sin registrarlos
+	registeredMiddlewares = map[string]definitions.Middleware{
+		"recovery":    middlewares.RecoveryMiddleware,
+		"cors":        middlewares.CorsMiddleware,
+		"access":      middlewares.AccessMiddleware,
+		"access_log":  middlewares.AccessLogMiddleware,
+		"auth":        middlewares.AuthMiddleware,
+		"compression": middlewares.CompressionMiddleware,
+		"concurrency": middlewares.ConcurrencyMiddleware,
+		"etag":        middlewares.ETagMiddleware,
+		"metrics":     middlewares.MetricsMiddleware,
+		"mtls":        middlewares.MtlsMiddleware,
+		"signature":   middlewares.SignatureMiddleware,
+		"tracing":     middlewares.TracingMiddleware,
+	}
+)
+
+// RegisterHandler registra (o reemplaza) un handler que los archivos de rutas usan por nombre
+func RegisterHandler(name string, handler http.HandlerFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	handlers[name] = handler
+}
+
+// RegisterMiddleware registra (o reemplaza) un middleware que los archivos de rutas usan por nombre
+func RegisterMiddleware(name string, middleware definitions.Middleware) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registeredMiddlewares[name] = middleware
+}
+
+type routeFile struct {
+	Groups    []routeFileGroup    `json:"groups" yaml:"groups"`
+	Overrides []routeFileOverride `json:"overrides" yaml:"overrides"`
+}
+
+type routeFileGroup struct {
+	Prefix      string           `json:"prefix" yaml:"prefix"`
+	Host        string           `json:"host" yaml:"host"`
+	Middlewares []string         `json:"middlewares" yaml:"middlewares"`
+	Timeout     routeFileTimeout `json:"timeout" yaml:"timeout"`
+	Routes      []routeFileRoute `json:"routes" yaml:"routes"`
+	Groups      []routeFileGroup `json:"groups" yaml:"groups"`
+}
+
+type routeFileRoute struct {
+	Path               string                 `json:"path" yaml:"path"`
+	Method             string                 `json:"method" yaml:"method"`
+	Handler            string                 `json:"handler" yaml:"handler"`
+	Name               string                 `json:"name" yaml:"name"`
+	Disabled           bool                   `json:"disabled" yaml:"disabled"`
+	Auth               *definitions.RouteAuth `json:"auth" yaml:"auth"`
+	Middlewares        []string               `json:"middlewares" yaml:"middlewares"`
+	ExcludeMiddlewares []string               `json:"exclude_middlewares" yaml:"exclude_middlewares"`
+	MiddlewareParams   map[string]interface{} `json:"middleware_params" yaml:"middleware_params"`
+	Timeout            routeFileTimeout       `json:"timeout" yaml:"timeout"`
+}
+
+// routeFileOverride modifica una ruta definida en Go por nombre y/o patrón
+type routeFileOverride struct {
+	Name               string                 `json:"name" yaml:"name"`
+	Pattern            string                 `json:"pattern" yaml:"pattern"`
+	Disabled           bool                   `json:"disabled" yaml:"disabled"`
+	Auth               *definitions.RouteAuth `json:"auth" yaml:"auth"`
+	Middlewares        []string               `json:"middlewares" yaml:"middlewares"`
+	ExcludeMiddlewares []string               `json:"exclude_middlewares" yaml:"exclude_middlewares"`
+	MiddlewareParams   map[string]interface{} `json:"middleware_params" yaml:"middleware_params"`
+	Timeout            routeFileTimeout       `json:"timeout" yaml:"timeout"`
+}
+
+// routeFileTimeout acepta una duración ("30s", "1m", "-1s") o un número de segundos como las
+// variables de entorno, se valida junto con el resto del archivo
+type routeFileTimeout string
+
+func (t *routeFileTimeout) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if value != nil {
+		*t = routeFileTimeout(fmt.Sprint(value))
+	}
+
+	return nil
+}
+
+func (t routeFileTimeout) duration(location string, errs *[]error) time.Duration {
+	value := strings.TrimSpace(string(t))
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		*errs = append(*errs, errors.New(location+": invalid timeout \""+value+"\""))
+		return 0
+	}
+
+	return duration
+}
+
+// LoadRouteFile lee un archivo de rutas YAML (.yaml, .yml) o JSON y valida los handlers y
+// middlewares contra los registrados. Los grupos se pasan a LoadRoutes junto con los definidos en
+// Go; al ir primero sus rutas tienen prioridad sobre las de Go con el mismo path y método, y los
+// overrides del archivo (en un grupo sin rutas) modifican o desactivan las rutas de Go
+func LoadRouteFile(path string) ([]definitions.RouteGroup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := "json"
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		format = "yaml"
+	}
+
+	return ParseRouteFile(data, format)
+}
+
+// ParseRouteFile interpreta el contenido de un archivo de rutas en formato "yaml" o "json", los
+// errores de validación se regresan todos juntos con la ubicación de cada uno
+func ParseRouteFile(data []byte, format string) ([]definitions.RouteGroup, error) {
+	var file routeFile
+
+	switch format {
+	case "yaml", "yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil {
+			return nil, errors.New("invalid route file: " + err.Error())
+		}
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, errors.New("invalid route file: " + err.Error())
+		}
+	default:
+		return nil, errors.New("unsupported route file format: " + format)
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var errs []error
+	groups := make([]definitions.RouteGroup, 0, len(file.Groups))
+	for i, group := range file.Groups {
+		groups = append(groups, buildFileGroup(group, "groups["+strconv.Itoa(i)+"]", &errs))
+	}
+
+	if len(file.Overrides) > 0 {
+		overrides := make([]definitions.RouteOverride, 0, len(file.Overrides))
+		for i, override := range file.Overrides {
+			if overrideDef, ok := buildFileOverride(override, "overrides["+strconv.Itoa(i)+"]", &errs); ok {
+				overrides = append(overrides, overrideDef)
+			}
+		}
+
+		groups = append(groups, definitions.RouteGroup{Overrides: overrides})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return groups, nil
+}
+
+func buildFileGroup(group routeFileGroup, location string, errs *[]error) definitions.RouteGroup {
+	routeGroup := definitions.RouteGroup{
+		Prefix:      group.Prefix,
+		Host:        group.Host,
+		Middlewares: fileMiddlewares(group.Middlewares, location+".middlewares", errs),
+		Timeout:     group.Timeout.duration(location+".timeout", errs),
+	}
+
+	for i, route := range group.Routes {
+		routeLocation := location + ".routes[" + strconv.Itoa(i) + "]"
+		if route.Disabled {
+			continue
+		}
+
+		if routeDef, ok := buildFileRoute(route, routeLocation, errs); ok {
+			routeGroup.Routes = append(routeGroup.Routes, routeDef)
+		}
+	}
+
+	for i, subGroup := range group.Groups {
+		routeGroup.Routes = append(routeGroup.Routes, buildFileGroup(subGroup, location+".groups["+strconv.Itoa(i)+"]", errs))
+	}
+
+	return routeGroup
+}
+
+func buildFileRoute(route routeFileRoute, location string, errs *[]error) (definitions.Route, bool) {
+	valid := true
+	fail := func(message string) {
+		*errs = append(*errs, errors.New(location+": "+message))
+		valid = false
+	}
+
+	if route.Path == "" {
+		fail("path is required")
+	}
+
+	method := strings.ToUpper(route.Method)
+	if !validMethod(method) {
+		fail("invalid method \"" + route.Method + "\"")
+	}
+
+	handler, exists := handlers[route.Handler]
+	if !exists {
+		fail("handler \"" + route.Handler + "\" is not registered")
+	}
+
+	routeDef := definitions.Route{
+		Path:               route.Path,
+		Method:             method,
+		Action:             handler,
+		Name:               route.Name,
+		Auth:               route.Auth,
+		Middlewares:        fileMiddlewares(route.Middlewares, location+".middlewares", errs),
+		ExcludeMiddlewares: fileMiddlewares(route.ExcludeMiddlewares, location+".exclude_middlewares", errs),
+		Timeout:            route.Timeout.duration(location+".timeout", errs),
+	}
+
+	if route.MiddlewareParams != nil {
+		params := route.MiddlewareParams
+		routeDef.MiddlewareParams = &params
+	}
+
+	return routeDef, valid
+}
+
+func buildFileOverride(override routeFileOverride, location string, errs *[]error) (definitions.RouteOverride, bool) {
+	valid := true
+	fail := func(message string) {
+		*errs = append(*errs, errors.New(location+": "+message))
+		valid = false
+	}
+
+	if override.Name == "" && override.Pattern == "" {
+		fail("name or pattern is required")
+	}
+
+	if method, _ := overridePattern(override.Pattern); method != "" && !validMethod(method) {
+		fail("invalid method in pattern \"" + override.Pattern + "\"")
+	}
+
+	overrideDef := definitions.RouteOverride{
+		Name:               override.Name,
+		Pattern:            override.Pattern,
+		Disabled:           override.Disabled,
+		Auth:               override.Auth,
+		Middlewares:        fileMiddlewares(override.Middlewares, location+".middlewares", errs),
+		ExcludeMiddlewares: fileMiddlewares(override.ExcludeMiddlewares, location+".exclude_middlewares", errs),
+		Timeout:            override.Timeout.duration(location+".timeout", errs),
+	}
+
+	if override.MiddlewareParams != nil {
+		params := override.MiddlewareParams
+		overrideDef.MiddlewareParams = &params
+	}
+
+	return overrideDef, valid
+}
+
+func fileMiddlewares(names []string, location string, errs *[]error) *[]definitions.Middleware {
+	if len(names) == 0 {
+		return nil
+	}
+
+	mws := make([]definitions.Middleware, 0, len(names))
+	for _, name := range names {
+		mw, exists := registeredMiddlewares[name]
+		if !exists {
+			*errs = append(*errs, errors.New(location+": middleware \""+name+"\" is not registered"))
+			continue
+		}
+
+		mws = append(mws, mw)
+	}
+
+	return &mws
+}
+
+func validMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
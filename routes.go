@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/Nemutagk/godb/definitions/db"
 	"github.com/Nemutagk/goenvars"
@@ -19,22 +21,56 @@ import (
 )
 
 func LoadRoutes(list_routes []definitions.RouteGroup, server *http.ServeMux, dbConnectionsList map[string]db.DbConnection) *http.ServeMux {
-	defaultMiddlewares := []definitions.Middleware{
-		middlewares.CorsMiddleware,
-		middlewares.AccessMiddleware,
-	}
+	defaultMiddlewares := loadDefaultMiddlewares()
+	metricsEnabled := goenvars.GetEnvBool("GOROUTES_METRICS_ENABLED", false)
+	notFoundEnabled := goenvars.GetEnvBool("GOROUTES_NOT_FOUND_ENABLED", true)
 
 	globalRouteList := map[string]definitions.Route{}
 
 	for _, gr := range list_routes {
-		tmpRoutes := checkRoute(gr, "/", defaultMiddlewares)
+		tmpRoutes := checkRoute(gr, "/", defaultMiddlewares, 0)
 		for path, route := range tmpRoutes {
-			if _, ok := globalRouteList[path]; ok {
+			existing, ok := globalRouteList[path]
+			if !ok {
+				globalRouteList[path] = route
+				continue
+			}
+
+			// el mismo path en otro grupo (ej. un archivo de rutas) agrega sus métodos
+			merged, ok := mergeRoutes(path, existing, route)
+			if !ok {
 				golog.Error(context.Background(), "Route already exists:", path, "Method:", route.Method)
 				continue
 			}
 
-			globalRouteList[path] = route
+			globalRouteList[path] = merged
+		}
+	}
+
+	for _, gr := range list_routes {
+		applyOverrides(globalRouteList, gr.Overrides)
+	}
+
+	registerRouteNames(globalRouteList)
+
+	// rutas de health y readiness, si la aplicación ya define alguna de esas rutas se respeta la suya
+	if goenvars.GetEnvBool("GOROUTES_HEALTH_ENABLED", true) {
+		if dbConnectionsList != nil {
+			RegisterHealthCheck("mongo", MongoHealthCheck(dbConnectionsList), 0)
+		}
+
+		for path, route := range checkRoute(HealthRoutes(), "/", defaultMiddlewares, 0) {
+			if _, ok := globalRouteList[path]; !ok && !patternRegistered(server, path) {
+				globalRouteList[path] = route
+			}
+		}
+	}
+
+	if metricsEnabled {
+		for path, route := range checkRoute(MetricsRoutes(), "/", defaultMiddlewares, 0) {
+			if _, ok := globalRouteList[path]; !ok && !patternRegistered(server, path) {
+				globalRouteList[path] = route
+			}
 		}
 	}
 
@@ -42,17 +78,105 @@ func LoadRoutes(list_routes []definitions.RouteGroup, server *http.ServeMux, dbC
 		showRoutesExists(globalRouteList)
 	}
 
+	methodNotAllowed := unmatchedAction(getMethodNotAllowedHandler, defaultMiddlewares, dbConnectionsList)
+
+	hosts := getHostRouter(server)
+	wildcardPatterns := map[string]bool{}
+	hostlessPatterns := map[string]bool{}
+
 	for path, route := range globalRouteList {
-		// log.Println("Registering route:", route.Path, "Method:", route.Method)
-		server.HandleFunc(path, applyMiddleware(route, dbConnectionsList))
+		// "/" en ServeMux coincide con cualquier path, con el catch-all activo la ruta raíz se
+		// registra solo para "/" exacto y el resto queda para el handler de not found
+		pattern := path
+		if notFoundEnabled && strings.HasSuffix(pattern, "/") && route.Pattern == "/" && !isMount(route) {
+			pattern += "{$}"
+		}
+
+		handler := applyMiddleware(route, dbConnectionsList, methodNotAllowed)
+
+		switch {
+		case isWildcardHost(route.Host):
+			hostPattern := strings.TrimPrefix(pattern, route.Host)
+			hosts.handle(route.Host, hostPattern, handler)
+			wildcardPatterns[hostPattern] = true
+		case route.Host != "":
+			server.HandleFunc(pattern, handler)
+		default:
+			server.HandleFunc(pattern, hosts.dispatch(handler))
+			hostlessPatterns[pattern] = true
+		}
+	}
+
+	// las rutas de hosts con parámetros se alcanzan desde el mux principal con dispatch, si ninguna
+	// ruta sin host tiene el patrón se registra uno propio y los demás hosts reciben lo que el mux
+	// ya respondía en ese path (ej. el "/" de la aplicación) o 404
+	notFound := unmatchedAction(getNotFoundHandler, defaultMiddlewares, dbConnectionsList)
+	for pattern := range wildcardPatterns {
+		// "/" en el mux principal sería un catch-all, solo se registra la raíz exacta
+		mainPattern := pattern
+		if mainPattern == "/" {
+			mainPattern = "/{$}"
+		}
+
+		if hostlessPatterns[pattern] || hostlessPatterns[mainPattern] || patternRegistered(server, mainPattern) {
+			continue
+		}
+
+		fallback := notFound
+		if handler, registered := server.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: pattern}}); registered != "" {
+			fallback = handler.ServeHTTP
+		}
+
+		server.HandleFunc(mainPattern, hosts.dispatch(fallback))
+	}
+
+	// si la aplicación (o un Mount en "/") ya atiende todas las rutas no se registra el catch-all,
+	// tampoco en las siguientes llamadas de LoadRoutes con el mismo mux
+	if notFoundEnabled && !catchAllRegistered(server) {
+		server.HandleFunc("/", hosts.dispatch(unmatchedAction(getNotFoundHandler, defaultMiddlewares, dbConnectionsList)))
 	}
 
 	return server
 }
 
-func checkRoute(rg definitions.RouteGroup, parentPath string, parentMiddleware []definitions.Middleware) map[string]definitions.Route {
+// loadDefaultMiddlewares arma los middlewares que se aplican a todas las rutas según el entorno
+func loadDefaultMiddlewares() []definitions.Middleware {
+	defaultMiddlewares := []definitions.Middleware{
+		middlewares.RecoveryMiddleware,
+		middlewares.CorsMiddleware,
+		middlewares.AccessMiddleware,
+	}
+
+	// el límite de concurrencia va antes de AccessMiddleware para descartar la carga sin consultar Mongo
+	if goenvars.GetEnvBool("GOROUTES_CONCURRENCY_ENABLED", false) {
+		defaultMiddlewares = []definitions.Middleware{
+			middlewares.RecoveryMiddleware,
+			middlewares.CorsMiddleware,
+			middlewares.ConcurrencyMiddleware,
+			middlewares.AccessMiddleware,
+		}
+	}
+
+	if goenvars.GetEnvBool("GOROUTES_TRACING_ENABLED", false) {
+		defaultMiddlewares = append([]definitions.Middleware{middlewares.TracingMiddleware}, defaultMiddlewares...)
+	}
+
+	// las métricas van primero para medir también las respuestas de RecoveryMiddleware
+	if goenvars.GetEnvBool("GOROUTES_METRICS_ENABLED", false) {
+		defaultMiddlewares = append([]definitions.Middleware{middlewares.MetricsMiddleware}, defaultMiddlewares...)
+	}
+
+	return defaultMiddlewares
+}
+
+func checkRoute(rg definitions.RouteGroup, parentPath string, parentMiddleware []definitions.Middleware, parentTimeout time.Duration) map[string]definitions.Route {
 	basePath := preparePath(rg.Prefix, parentPath)
 
+	// si el grupo no define un timeout hereda el del grupo padre
+	if rg.Timeout <= 0 {
+		rg.Timeout = parentTimeout
+	}
+
 	// Agregamos los middlewares del grupo padre
 	if rg.Middlewares != nil && len(*rg.Middlewares) > 0 {
 		for _, md := range *rg.Middlewares {
@@ -66,10 +190,24 @@ func checkRoute(rg definitions.RouteGroup, parentPath string, parentMiddleware [
 
 	// Listamos todas las rutas del grupo
 	for _, route := range rg.Routes {
+		// un VersionedGroup genera las rutas de cada versión y las del path sin versión
+		if versioned, ok := route.(definitions.VersionedGroup); ok {
+			for path, versionRoute := range versionedRoutes(versioned, basePath, parentMiddleware, rg.Timeout) {
+				if _, exists := allRoutes[path]; exists {
+					golog.Error(context.Background(), "Route already exists:", path)
+					continue
+				}
+
+				allRoutes[path] = versionRoute
+			}
+
+			continue
+		}
+
 		// validamos si la ruta a checar es otro grupo (subgrupo)
 		if subroute, ok := route.(definitions.RouteGroup); ok {
 			// si es un subgrupo, llamamos recursivamente a checkRoute
-			tmpRoutes := checkRoute(subroute, basePath, parentMiddleware)
+			tmpRoutes := checkRoute(subroute, basePath, parentMiddleware, rg.Timeout)
 			// agregamos las rutas del subgrupo a la lista de rutas
 			for path, subRoute := range tmpRoutes {
 				allRoutes[path] = subRoute
@@ -78,6 +216,23 @@ func checkRoute(rg definitions.RouteGroup, parentPath string, parentMiddleware [
 			continue
 		}
 
+		// un Mount se registra como una ruta para cualquier método bajo "prefijo/"
+		if mount, ok := route.(definitions.Mount); ok {
+			if mount.Handler == nil {
+				golog.Error(context.Background(), "Mount without handler:", mount.Prefix)
+				continue
+			}
+
+			mountDef := mountRoute(mount, basePath)
+			if _, exists := allRoutes[mountDef.Pattern]; exists {
+				golog.Error(context.Background(), "Mount already exists:", mountDef.Pattern)
+				continue
+			}
+
+			allRoutes[mountDef.Pattern] = mountDef
+			continue
+		}
+
 		// si no es un subgrupo, validamos que sea una ruta
 		routeDef, ok := route.(definitions.Route)
 		if !ok {
@@ -96,76 +251,76 @@ func checkRoute(rg definitions.RouteGroup, parentPath string, parentMiddleware [
 		// Verificamos que las rutas tengan los middlewares del grupo padre incluidos
 		// middlewares que deben cargar por default
 		if route.Group == nil {
-			allRoutes[path] = addMiddleware(route, parentMiddleware)
+			allRoutes[path] = addTimeout(addMiddleware(route, parentMiddleware), rg.Timeout)
 			continue
 		}
 
 		// Si la ruta tiene un grupo, agregamos los middlewares del grupo padre a cada subruta
 		for method, subRoute := range route.Group {
-			route.Group[method] = addMiddleware(subRoute, parentMiddleware)
+			route.Group[method] = addTimeout(addMiddleware(subRoute, parentMiddleware), rg.Timeout)
 		}
 
 		allRoutes[path] = route
 	}
 
-	return allRoutes
+	return withHost(allRoutes, rg.Host)
 }
 
 func addMiddleware(route definitions.Route, parentMiddleware []definitions.Middleware) definitions.Route {
-	copySlice := func(in []definitions.Middleware) []definitions.Middleware {
-		out := make([]definitions.Middleware, len(in))
-		copy(out, in)
-		return out
+	// Primero van los middlewares definidos en la ruta y después los del padre que no estén
+	// ya definidos en la ruta ni excluidos, buildAction pone los outerMiddlewares por fuera
+	mws := []definitions.Middleware{}
+	if route.Middlewares != nil {
+		mws = append(mws, *route.Middlewares...)
 	}
 
-	if route.Middlewares == nil || len(*route.Middlewares) == 0 {
-		if route.ExcludeMiddlewares == nil || len(*route.ExcludeMiddlewares) == 0 {
-			mws := copySlice(parentMiddleware)
-			route.Middlewares = &mws
-			return route
+	for _, md := range parentMiddleware {
+		if containsMiddleware(mws, md) {
+			continue
 		}
 
-		// Si hay exclusión de middlewares, se agregan los del padre menos los excluidos
-		for _, emd := range *route.ExcludeMiddlewares {
-			if !containsMiddleware(parentMiddleware, emd) {
-				tmpRoutes := append(*route.Middlewares, emd)
-				route.Middlewares = &tmpRoutes
-			}
+		if route.ExcludeMiddlewares != nil && containsMiddleware(*route.ExcludeMiddlewares, md) {
+			continue
 		}
-	} else {
-		// Si ya hay middlewares definidos en la ruta, se agregan los del padre menos los excluidos
-		// ni tampoco los que ya están definidos en la ruta
-		mdPreparados := []definitions.Middleware{}
-		for _, md := range parentMiddleware {
-			if route.ExcludeMiddlewares == nil || len(*route.ExcludeMiddlewares) == 0 {
-				if !containsMiddleware(*route.Middlewares, md) {
-					mdPreparados = append(mdPreparados, md)
-				}
 
-				continue
-			}
+		mws = append(mws, md)
+	}
 
-			for _, emd := range *route.ExcludeMiddlewares {
-				if !containsMiddleware(*route.Middlewares, emd) {
-					mdPreparados = append(mdPreparados, emd)
-				}
-			}
-		}
+	route.Middlewares = &mws
+	return route
+}
 
-		for _, mdP := range mdPreparados {
-			if !containsMiddleware(*route.Middlewares, mdP) {
-				tmpMd := append(*route.Middlewares, mdP)
-				route.Middlewares = &tmpMd
-			}
-		}
+func addTimeout(route definitions.Route, groupTimeout time.Duration) definitions.Route {
+	// un timeout negativo desactiva el del grupo (ej. WebSocket o streaming)
+	if route.Timeout == 0 {
+		route.Timeout = groupTimeout
 	}
 
 	return route
 }
 
+// patternRegistered indica si el mux ya tiene el patrón, las rutas internas (health, métricas,
+// not found) no se registran dos veces cuando LoadRoutes se llama varias veces con el mismo mux
+func patternRegistered(server *http.ServeMux, path string) bool {
+	_, pattern := server.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}})
+	return pattern == path
+}
+
+// catchAllRegistered indica si el mux ya tiene un patrón sin método que coincide con cualquier
+// path ("/" o "/{path...}"), registrar otro haría que ServeMux entre en pánico
+func catchAllRegistered(server *http.ServeMux) bool {
+	_, pattern := server.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/goroutes/catch-all"}})
+	if pattern == "/" {
+		return true
+	}
+
+	return strings.HasPrefix(pattern, "/{") && strings.HasSuffix(pattern, "...}") && strings.Count(pattern, "/") == 1
+}
+
 func routeExists(routeList map[string]definitions.Route, parentPath string, route definitions.Route) map[string]definitions.Route {
 	//generamos la ruta completa a partir del prefijo y el path del padre
 	path := preparePath(route.Path, parentPath)
+	route.Pattern = path
 
 	// si es una ruta que no existe en el grupo global, la agregamos
 	if _, exists := routeList[path]; !exists {
@@ -235,64 +390,116 @@ func containsMiddleware(middleware []definitions.Middleware, mw definitions.Midd
 	return false
 }
 
-func applyMiddleware(route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		awsHealthChecker := r.Header.Get("User-Agent")
+func applyMiddleware(route definitions.Route, dbListConn map[string]db.DbConnection, methodNotAllowed http.HandlerFunc) http.HandlerFunc {
+	// el path sin versión de un VersionedGroup elige la ruta de la versión en cada petición
+	if len(route.Versions) > 0 {
+		return versionDispatch(route, dbListConn, methodNotAllowed)
+	}
+
+	// las cadenas de middlewares se construyen una sola vez al registrar la ruta y no en cada petición
+	action := buildAction(route, dbListConn)
+
+	subActions := map[string]http.HandlerFunc{}
+	for method, subRoute := range route.Group {
+		subActions[method] = buildAction(subRoute, dbListConn)
+	}
+	allow := allowedMethods(route)
 
-		if goenvars.GetEnvBool("GOROUTES_DISABLED_AWS_HEALTH_CHECKER", true) && strings.Contains(awsHealthChecker, "ELB-HealthChecker") {
-			// Si el header User-Agent contiene "ELB-HealthChecker", retornamos un 200 OK
+	return func(w http.ResponseWriter, r *http.Request) {
+		// el health checker de AWS solo se responde directamente en la ruta configurada
+		// en GOROUTES_AWS_HEALTH_CHECKER_PATH, en cualquier otra ruta pasa por los middlewares
+		awsHealthPath := goenvars.GetEnv("GOROUTES_AWS_HEALTH_CHECKER_PATH", "")
+		if awsHealthPath != "" && r.URL.Path == awsHealthPath && strings.Contains(r.Header.Get("User-Agent"), "ELB-HealthChecker") {
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("OK!"))
 			return
 		}
 
 		// si la ruta no tiene grupo ejecutamos retornamos la acción directamente
-		if route.Group == nil || len(route.Group) == 0 {
-			if route.Middlewares != nil && len(*route.Middlewares) > 0 {
-				for i := len(*route.Middlewares) - 1; i >= 0; i-- {
-					route.Action = (*route.Middlewares)[i](route.Action, route, dbListConn)
-				}
-			}
-
+		if len(route.Group) == 0 {
 			if r.Method == http.MethodOptions {
-				route.Action = middlewares.CorsMiddleware(route.Action, route, dbListConn)
-				route.Action(w, r)
+				middlewares.CorsMiddleware(action, route, dbListConn)(w, r)
 				return
 			}
 
-			if r.Method != route.Method {
-				golog.Error(context.Background(), "Method not allowed:", r.Method, "for route:", r.URL.Path)
-				GoErrorResponse(w, *goerrors.NewGError("Method not allowed", goerrors.StatusMethodNotAllowed, nil, nil))
+			if r.Method != route.Method && !isMount(route) {
+				golog.Error(r.Context(), "Method not allowed:", r.Method, "for route:", r.URL.Path)
+				w.Header().Set("Allow", allow)
+				methodNotAllowed(w, r)
 				return
 			}
 
-			route.Action(w, r)
+			action(w, r)
 			return
 		}
 		// si la ruta tiene un grupo, buscamos el subgrupo correspondiente al método de la ruta que
 		// se está ejecutando
 
+		subAction, exists := subActions[r.Method]
 		if r.Method == http.MethodOptions {
-			subRouteTmp := route.Group[r.Method]
-			subRouteTmp.Action = middlewares.CorsMiddleware(subRouteTmp.Action, route, dbListConn)
-			route.Group[r.Method] = subRouteTmp
+			middlewares.CorsMiddleware(subAction, route, dbListConn)(w, r)
+			return
 		}
 
-		subRoute, exists := route.Group[r.Method]
 		if !exists {
-			golog.Error(context.Background(), "No sub-route found for method:", r.Method, "in group:", r.URL.Path)
-			GoErrorResponse(w, *goerrors.NewGError("Method not allowed", goerrors.StatusMethodNotAllowed, nil, nil))
+			golog.Error(r.Context(), "No sub-route found for method:", r.Method, "in group:", r.URL.Path)
+			w.Header().Set("Allow", allow)
+			methodNotAllowed(w, r)
 			return
 		}
 
-		if subRoute.Middlewares != nil && len(*subRoute.Middlewares) > 0 {
-			for i := len(*subRoute.Middlewares) - 1; i >= 0; i-- {
-				subRoute.Action = (*subRoute.Middlewares)[i](subRoute.Action, subRoute, dbListConn)
-			}
+		subAction(w, r)
+	}
+}
+
+// buildAction envuelve la acción de la ruta con el timeout (el más interno) y sus middlewares
+func buildAction(route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	action := route.Action
+
+	if route.Timeout > 0 {
+		action = middlewares.TimeoutMiddleware(action, route, dbListConn)
+	}
+
+	if route.Middlewares != nil && len(*route.Middlewares) > 0 {
+		mws := sortMiddlewares(*route.Middlewares)
+		for i := len(mws) - 1; i >= 0; i-- {
+			action = mws[i](action, route, dbListConn)
 		}
+	}
+
+	return action
+}
 
-		subRoute.Action(w, r)
+// outerMiddlewares son los middlewares de infraestructura en el orden en que envuelven la cadena,
+// van siempre por fuera de los middlewares de la ruta y del grupo (auth, firma, mTLS) para que sus
+// panics lleguen a RecoveryMiddleware y sus respuestas queden en las métricas y logs de acceso
+var outerMiddlewares = []definitions.Middleware{
+	middlewares.MetricsMiddleware,
+	middlewares.TracingMiddleware,
+	middlewares.RecoveryMiddleware,
+	middlewares.CorsMiddleware,
+	middlewares.ConcurrencyMiddleware,
+	middlewares.AccessMiddleware,
+	middlewares.AccessLogMiddleware,
+}
+
+// sortMiddlewares ordena la cadena de una ruta, primero los outerMiddlewares que tenga y después
+// los demás en el orden en que se definieron
+func sortMiddlewares(mws []definitions.Middleware) []definitions.Middleware {
+	sorted := make([]definitions.Middleware, 0, len(mws))
+	for _, md := range outerMiddlewares {
+		if containsMiddleware(mws, md) {
+			sorted = append(sorted, md)
+		}
+	}
+
+	for _, md := range mws {
+		if !containsMiddleware(outerMiddlewares, md) {
+			sorted = append(sorted, md)
+		}
 	}
+
+	return sorted
 }
 
 func GoErrorResponse(w http.ResponseWriter, err goerrors.GError) {
@@ -375,6 +582,18 @@ func showRoutesExists(routeList map[string]definitions.Route) {
 	txtInfo := "======================================================\n" + "Registered routes:\n"
 	for _, path := range keys {
 		route := routeList[path]
+		if len(route.Versions) > 0 {
+			names := make([]string, 0, len(route.Versions))
+			for name := range route.Versions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			txtInfo += "VERSION\t" + path + "\t[" + strings.Join(names, ", ") + "]\n"
+			totalRoutes++
+			continue
+		}
+
 		if route.Group == nil {
 			txtInfo += getInfoRoute(route, path)
 			totalRoutes++
@@ -393,12 +612,21 @@ func showRoutesExists(routeList map[string]definitions.Route) {
 }
 
 func getInfoRoute(route definitions.Route, path string) string {
-	txtInfo := route.Method + "\t" + path + "\n"
+	method := route.Method
+	if isMount(route) {
+		method = "MOUNT"
+	}
+
+	txtInfo := method + "\t" + path
+	if route.Name != "" {
+		txtInfo += "\t(" + route.Name + ")"
+	}
+	txtInfo += "\n"
 
 	if goenvars.GetEnvBool("GOROUTES_DEBUG_MIDDLEWARES", false) {
 		if route.Middlewares != nil && len(*route.Middlewares) > 0 {
 			txtInfo += fmt.Sprintf("\tMiddlewares (%d):\n", len(*route.Middlewares))
-			for _, mw := range *route.Middlewares {
+			for _, mw := range sortMiddlewares(*route.Middlewares) {
 				txtInfo += "\t\t" + funcName(mw) + "\n"
 			}
 			txtInfo += "\n"
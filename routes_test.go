@@ -0,0 +1,199 @@
+package goroutes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/metrics"
+	"github.com/Nemutagk/goroutes/middlewares"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "goroutes")
+	if err != nil {
+		panic(err)
+	}
+
+	golog.Init(map[string]db.DbConnection{}, golog.WithFileDriver(filepath.Join(dir, "goroutes.log"), false))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// withoutAccess excluye AccessMiddleware, sin conexiones a Mongo responde 500
+var withoutAccess = &[]definitions.Middleware{middlewares.AccessMiddleware}
+
+func serveRoute(mux *http.ServeMux, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+
+	return rec
+}
+
+func panicMiddleware(next http.HandlerFunc, route definitions.Route, dbListConn map[string]db.DbConnection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		panic("route middleware panic")
+	}
+}
+
+func TestRouteMiddlewarePanicRecovered(t *testing.T) {
+	routeMws := []definitions.Middleware{panicMiddleware}
+	groupMws := []definitions.Middleware{panicMiddleware}
+
+	mux := http.NewServeMux()
+	LoadRoutes([]definitions.RouteGroup{
+		{Prefix: "/route", Routes: []interface{}{
+			definitions.Route{Path: "/panic", Method: http.MethodGet, Middlewares: &routeMws, ExcludeMiddlewares: withoutAccess, Action: func(w http.ResponseWriter, r *http.Request) {}},
+		}},
+		{Prefix: "/group", Middlewares: &groupMws, Routes: []interface{}{
+			definitions.Route{Path: "/panic", Method: http.MethodGet, ExcludeMiddlewares: withoutAccess, Action: func(w http.ResponseWriter, r *http.Request) {}},
+		}},
+	}, mux, nil)
+
+	for _, target := range []string{"/route/panic", "/group/panic"} {
+		rec := serveRoute(mux, http.MethodGet, target)
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("%s: expected 500 from RecoveryMiddleware, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestSortMiddlewares(t *testing.T) {
+	mws := sortMiddlewares([]definitions.Middleware{
+		middlewares.AuthMiddleware,
+		middlewares.AccessLogMiddleware,
+		panicMiddleware,
+		middlewares.CorsMiddleware,
+		middlewares.RecoveryMiddleware,
+	})
+
+	expected := []definitions.Middleware{
+		middlewares.RecoveryMiddleware,
+		middlewares.CorsMiddleware,
+		middlewares.AccessLogMiddleware,
+		middlewares.AuthMiddleware,
+		panicMiddleware,
+	}
+
+	if len(mws) != len(expected) {
+		t.Fatalf("expected %d middlewares, got %d", len(expected), len(mws))
+	}
+
+	for i, md := range expected {
+		if funcName(mws[i]) != funcName(md) {
+			t.Fatalf("position %d: expected %s, got %s", i, funcName(md), funcName(mws[i]))
+		}
+	}
+}
+
+func TestConcurrencyMiddlewareOrder(t *testing.T) {
+	routeMws := []definitions.Middleware{middlewares.AuthMiddleware, middlewares.ConcurrencyMiddleware}
+	route := addMiddleware(definitions.Route{Middlewares: &routeMws}, loadDefaultMiddlewares())
+
+	expected := []string{"RecoveryMiddleware", "CorsMiddleware", "ConcurrencyMiddleware", "AccessMiddleware", "AuthMiddleware"}
+	mws := sortMiddlewares(*route.Middlewares)
+
+	if len(mws) != len(expected) {
+		t.Fatalf("expected %d middlewares, got %d", len(expected), len(mws))
+	}
+
+	for i, name := range expected {
+		if funcName(mws[i]) != name {
+			t.Fatalf("position %d: expected %s, got %s", i, name, funcName(mws[i]))
+		}
+	}
+}
+
+type tokenAuthenticator map[string]any
+
+func (a tokenAuthenticator) Authenticate(ctx context.Context, token string, auth definitions.RouteAuth) (any, error) {
+	if principal, ok := a[token]; ok {
+		return principal, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+func TestRouteAuthInAccessLog(t *testing.T) {
+	t.Setenv("GOROUTES_ACCESS_LOG_PRINCIPAL_FIELDS", "sub")
+
+	var logs bytes.Buffer
+	middlewares.AddAccessLogSink(middlewares.NewJsonAccessLogSink(&logs))
+
+	middlewares.SetAuthenticator(tokenAuthenticator{"good": map[string]any{"sub": "user-1", "email": "user@example.com"}})
+	t.Cleanup(func() { middlewares.SetAuthenticator(nil) })
+
+	groupMws := []definitions.Middleware{middlewares.AccessLogMiddleware}
+	routeMws := []definitions.Middleware{middlewares.AuthMiddleware}
+
+	mux := http.NewServeMux()
+	LoadRoutes([]definitions.RouteGroup{{Prefix: "/logged", Middlewares: &groupMws, Routes: []interface{}{
+		definitions.Route{Path: "/me", Method: http.MethodGet, Auth: &definitions.RouteAuth{}, Middlewares: &routeMws, ExcludeMiddlewares: withoutAccess, Action: func(w http.ResponseWriter, r *http.Request) {}},
+	}}}, mux, nil)
+
+	authorized := httptest.NewRequest(http.MethodGet, "/logged/me", nil)
+	authorized.Header.Set("Authorization", "good")
+	mux.ServeHTTP(httptest.NewRecorder(), authorized)
+
+	serveRoute(mux, http.MethodGet, "/logged/me")
+
+	entries := []middlewares.AccessLogEntry{}
+	decoder := json.NewDecoder(&logs)
+	for decoder.More() {
+		var entry middlewares.AccessLogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 access log entries, got %d", len(entries))
+	}
+
+	if entries[0].Status != http.StatusOK || entries[0].User != "user-1" || entries[0].Principal["sub"] != "user-1" {
+		t.Fatalf("expected the authenticated principal in the log, got %+v", entries[0])
+	}
+
+	if _, exists := entries[0].Principal["email"]; exists {
+		t.Fatalf("only allow-listed principal fields must be logged, got %+v", entries[0].Principal)
+	}
+
+	if entries[1].Status != http.StatusUnauthorized || entries[1].User != "" {
+		t.Fatalf("expected the 401 in the log, got %+v", entries[1])
+	}
+}
+
+func TestRouteMiddlewareResponsesInMetrics(t *testing.T) {
+	t.Setenv("GOROUTES_METRICS_ENABLED", "true")
+
+	routeMws := []definitions.Middleware{middlewares.AuthMiddleware}
+
+	mux := http.NewServeMux()
+	LoadRoutes([]definitions.RouteGroup{{Prefix: "/metered", Routes: []interface{}{
+		definitions.Route{Path: "/private", Method: http.MethodGet, Auth: &definitions.RouteAuth{}, Middlewares: &routeMws, ExcludeMiddlewares: withoutAccess, Action: func(w http.ResponseWriter, r *http.Request) {}},
+	}}}, mux, nil)
+
+	if rec := serveRoute(mux, http.MethodGet, "/metered/private"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+
+	var output bytes.Buffer
+	metrics.WriteText(&output)
+
+	if !strings.Contains(output.String(), `goroutes_http_requests_total{route="/metered/private",method="GET",status="4xx"} 1`) {
+		t.Fatalf("expected the 401 of the route middleware in the metrics, got:\n%s", output.String())
+	}
+}
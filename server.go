@@ -0,0 +1,242 @@
+package goroutes
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/helper"
+	"github.com/Nemutagk/goroutes/lifecycle"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ServerConfig struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ReadinessDelay es el tiempo que el servicio se reporta como no listo antes de comenzar el
+	// drenado, para que el balanceador deje de enviar tráfico
+	ReadinessDelay time.Duration
+	// DrainTimeout es el tiempo máximo para terminar las peticiones en curso
+	DrainTimeout time.Duration
+	TLSConfig    *tls.Config
+	// DbConnections son las conexiones que se cierran al apagar el servidor
+	DbConnections map[string]db.DbConnection
+}
+
+// Server envuelve el mux generado por LoadRoutes con un http.Server con timeouts y apagado
+// controlado por las señales SIGINT y SIGTERM
+type Server struct {
+	config     ServerConfig
+	httpServer *http.Server
+	hooks      []lifecycle.ShutdownHook
+	mu         sync.Mutex
+	stop       context.CancelFunc
+}
+
+// LoadServerConfigFromEnv genera la configuración a partir de las variables GOROUTES_SERVER_*
+func LoadServerConfigFromEnv() ServerConfig {
+	return ServerConfig{
+		Addr:              goenvars.GetEnv("GOROUTES_SERVER_ADDR", ":8080"),
+		ReadTimeout:       time.Duration(goenvars.GetEnvInt("GOROUTES_SERVER_READ_TIMEOUT", 30)) * time.Second,
+		ReadHeaderTimeout: time.Duration(goenvars.GetEnvInt("GOROUTES_SERVER_READ_HEADER_TIMEOUT", 10)) * time.Second,
+		WriteTimeout:      time.Duration(goenvars.GetEnvInt("GOROUTES_SERVER_WRITE_TIMEOUT", 60)) * time.Second,
+		IdleTimeout:       time.Duration(goenvars.GetEnvInt("GOROUTES_SERVER_IDLE_TIMEOUT", 120)) * time.Second,
+		ReadinessDelay:    time.Duration(goenvars.GetEnvInt("GOROUTES_SERVER_READINESS_DELAY", 5)) * time.Second,
+		DrainTimeout:      time.Duration(goenvars.GetEnvInt("GOROUTES_SERVER_DRAIN_TIMEOUT", 30)) * time.Second,
+	}
+}
+
+func NewServer(handler http.Handler, config ServerConfig) *Server {
+	return &Server{
+		config: config,
+		httpServer: &http.Server{
+			Addr:              config.Addr,
+			Handler:           handler,
+			ReadTimeout:       config.ReadTimeout,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+			TLSConfig:         config.TLSConfig,
+		},
+	}
+}
+
+// OnShutdown registra una función a ejecutar después de drenar las peticiones, los hooks del
+// servidor se ejecutan antes que los registrados en lifecycle.RegisterShutdownHook
+func (s *Server) OnShutdown(hook lifecycle.ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, hook)
+}
+
+// HttpServer retorna el http.Server interno para configuraciones adicionales
+func (s *Server) HttpServer() *http.Server {
+	return s.httpServer
+}
+
+// ListenAndServe inicia el servidor y bloquea hasta recibir SIGINT/SIGTERM y terminar el apagado
+func (s *Server) ListenAndServe() error {
+	return s.serve(func(ln net.Listener) error {
+		return s.httpServer.Serve(ln)
+	})
+}
+
+// ListenAndServeTLS igual que ListenAndServe pero sirviendo TLS con los archivos indicados
+// (pueden ir vacíos si TLSConfig ya contiene los certificados)
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	return s.serve(func(ln net.Listener) error {
+		return s.httpServer.ServeTLS(ln, certFile, keyFile)
+	})
+}
+
+// Shutdown inicia el apagado del servidor sin esperar una señal del sistema
+func (s *Server) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		s.stop()
+	}
+}
+
+func (s *Server) serve(serve func(ln net.Listener) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s.mu.Lock()
+	s.stop = stop
+	s.mu.Unlock()
+
+	lifecycle.SetReady(false)
+
+	ln, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- serve(ln)
+	}()
+
+	lifecycle.SetReady(true)
+	golog.Log(context.Background(), "Server listening on", ln.Addr().String())
+
+	select {
+	case err := <-errChan:
+		lifecycle.SetReady(false)
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	// una segunda señal durante el apagado omite la espera de readiness
+	forceCtx, forceStop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer forceStop()
+
+	return s.shutdown(forceCtx)
+}
+
+func (s *Server) shutdown(forceCtx context.Context) error {
+	ctx := context.Background()
+	golog.Log(ctx, "Shutting down server, marking as not ready")
+
+	// primero dejamos de reportarnos como listos para que el balanceador deje de enviar tráfico
+	lifecycle.SetReady(false)
+	s.waitReadinessDelay(forceCtx)
+
+	drainCtx, cancel := context.WithTimeout(ctx, s.config.DrainTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := s.httpServer.Shutdown(drainCtx); err != nil {
+		golog.Error(ctx, "Error draining requests:", err)
+		errs = append(errs, err)
+	}
+
+	hookCtx, cancelHooks := context.WithTimeout(ctx, s.config.DrainTimeout)
+	defer cancelHooks()
+
+	s.mu.Lock()
+	hooks := make([]lifecycle.ShutdownHook, len(s.hooks))
+	copy(hooks, s.hooks)
+	s.mu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](hookCtx); err != nil {
+			golog.Error(ctx, "Error running shutdown hook:", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if err := lifecycle.RunShutdownHooks(hookCtx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.config.DbConnections != nil {
+		if err := closeDbConnections(hookCtx, s.config.DbConnections); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	golog.Log(ctx, "Server stopped")
+	// al final vaciamos los logs pendientes de golog
+	golog.Close()
+
+	return errors.Join(errs...)
+}
+
+// waitReadinessDelay espera ReadinessDelay o hasta que forceCtx se cancele
+func (s *Server) waitReadinessDelay(forceCtx context.Context) {
+	delay := time.NewTimer(s.config.ReadinessDelay)
+	defer delay.Stop()
+
+	select {
+	case <-delay.C:
+	case <-forceCtx.Done():
+		golog.Warning(context.Background(), "Shutdown signal received again, skipping readiness delay")
+	}
+}
+
+// closeDbConnections cierra las conexiones solo si goroutes llegó a abrirlas, inicializarlas
+// aquí conectaría a Mongo únicamente para desconectarse
+func closeDbConnections(ctx context.Context, dbConnectionsList map[string]db.DbConnection) error {
+	manager := helper.OpenedDbConnections()
+	if manager == nil {
+		return nil
+	}
+
+	var errs []error
+	for name := range dbConnectionsList {
+		conn, err := manager.GetRawConnection(name)
+		if err != nil {
+			continue
+		}
+
+		if mongoDb, ok := conn.(*mongo.Database); ok {
+			// varias conexiones pueden compartir el mismo cliente
+			if err := mongoDb.Client().Disconnect(ctx); err != nil && !errors.Is(err, mongo.ErrClientDisconnected) {
+				golog.Error(ctx, "Error closing database connection:", name, err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
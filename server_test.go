@@ -0,0 +1,44 @@
+package goroutes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+)
+
+func TestWaitReadinessDelay(t *testing.T) {
+	server := NewServer(nil, ServerConfig{ReadinessDelay: 20 * time.Millisecond})
+
+	start := time.Now()
+	server.waitReadinessDelay(context.Background())
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected to wait the readiness delay, waited %s", elapsed)
+	}
+
+	server.config.ReadinessDelay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		server.waitReadinessDelay(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a second signal must skip the readiness delay")
+	}
+}
+
+func TestCloseDbConnectionsNotOpened(t *testing.T) {
+	// goroutes no abrió las conexiones, no debe intentar conectar para cerrarlas
+	conns := map[string]db.DbConnection{"logs": {Driver: "mongo"}}
+
+	if err := closeDbConnections(context.Background(), conns); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Nemutagk/goenvars"
 	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/tracing"
 )
 
 type HTTPError struct {
@@ -23,6 +24,11 @@ func (e *HTTPError) Error() string {
 }
 
 func AccountService(path, method string, payload interface{}) (any, error) {
+	return AccountServiceWithContext(context.Background(), path, method, payload)
+}
+
+// AccountServiceWithContext hace la petición al servicio de cuentas cancelándola junto con el contexto
+func AccountServiceWithContext(ctx context.Context, path, method string, payload interface{}) (result any, err error) {
 	baseUrl := goenvars.GetEnv("ACCOUNT_API_URL", "http://localhost:8080")
 
 	lastLetterBaseUrl := baseUrl[len(baseUrl)-1:]
@@ -42,7 +48,14 @@ func AccountService(path, method string, payload interface{}) (any, error) {
 
 	url := baseUrl + path
 	var requestBody []byte
-	var err error
+
+	ctx, span := tracing.StartSpanIfRecording(ctx, "account_service "+method+" "+path, tracing.SpanKindClient)
+	span.SetAttribute("http.request.method", method)
+	span.SetAttribute("url.full", url)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 
 	if payload != nil {
 		requestBody, err = json.Marshal(payload)
@@ -52,12 +65,15 @@ func AccountService(path, method string, payload interface{}) (any, error) {
 		}
 	}
 
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		fmt.Println("Error creating request:", err)
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if span != nil {
+		tracing.Inject(ctx, req.Header)
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -66,6 +82,7 @@ func AccountService(path, method string, payload interface{}) (any, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
 
 	body, err := io.ReadAll(resp.Body)
 
@@ -83,13 +100,13 @@ func AccountService(path, method string, payload interface{}) (any, error) {
 		}
 	}
 
-	var result map[string]any
+	var response map[string]any
 
-	err = json.Unmarshal(body, &result)
+	err = json.Unmarshal(body, &response)
 	if err != nil {
-		golog.Error(context.Background(), "Error decoding response:", err)
+		golog.Error(ctx, "Error decoding response:", err)
 		return nil, err
 	}
 
-	return result, nil
+	return response, nil
 }
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nemutagk/goroutes/tracing"
+)
+
+func TestAccountServiceTracing(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(tracing.TRACEPARENT_HEADER)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("ACCOUNT_API_URL", server.URL)
+	t.Cleanup(func() { tracing.SetExporter(nil) })
+
+	unsampled := tracing.ContextWithRemoteSpanContext(context.Background(), tracing.SpanContext{
+		TraceID: [16]byte{1},
+		SpanID:  [8]byte{1},
+	})
+
+	cases := []struct {
+		name     string
+		exporter bool
+		ratio    string
+		ctx      context.Context
+		recorded bool
+	}{
+		{"without exporter", false, "1", context.Background(), false},
+		{"sampled", true, "1", context.Background(), true},
+		{"new trace not sampled", true, "0", context.Background(), false},
+		{"remote trace not sampled", true, "1", unsampled, false},
+	}
+
+	for _, c := range cases {
+		t.Setenv("GOROUTES_TRACING_SAMPLE_RATIO", c.ratio)
+
+		var spans bytes.Buffer
+		tracing.SetExporter(nil)
+		if c.exporter {
+			tracing.SetExporter(tracing.NewStdoutExporter(&spans))
+		}
+
+		traceparent = ""
+		if _, err := AccountServiceWithContext(c.ctx, "/me", http.MethodGet, nil); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		if (traceparent != "") != c.recorded || (spans.Len() > 0) != c.recorded {
+			t.Fatalf("%s: expected recorded %v, got traceparent %q and %d bytes of spans", c.name, c.recorded, traceparent, spans.Len())
+		}
+	}
+}
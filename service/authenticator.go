@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// Authenticator valida el token de una petición contra los requisitos de la ruta (RouteAuth),
+// el valor retornado es el principal autenticado que se guarda en el contexto de la petición.
+// Los errores de tipo *HTTPError se regresan tal cual al cliente
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string, auth definitions.RouteAuth) (any, error)
+}
+
+// AccountAuthenticator valida el token contra el endpoint /auth/validation de ACCOUNT_API_URL
+type AccountAuthenticator struct{}
+
+func NewAccountAuthenticator() *AccountAuthenticator {
+	return &AccountAuthenticator{}
+}
+
+func (a *AccountAuthenticator) Authenticate(ctx context.Context, token string, auth definitions.RouteAuth) (any, error) {
+	return AccountServiceWithContext(ctx, "/auth/validation", "POST", map[string]string{
+		"token":      token,
+		"app":        auth.App,
+		"permission": auth.Permission,
+	})
+}
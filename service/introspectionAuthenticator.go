@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/goerrors"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+	"github.com/Nemutagk/goroutes/tracing"
+)
+
+// IntrospectionAuthenticator valida tokens OAuth 2.0 usando un endpoint de introspección (RFC 7662).
+//
+// El permiso de la ruta (RouteAuth.Permission) se busca dentro del scope del token, se acepta
+// tanto el permiso tal cual como con el prefijo de la aplicación ("app:permission")
+type IntrospectionAuthenticator struct {
+	Url          string
+	ClientId     string
+	ClientSecret string
+	Client       *http.Client
+}
+
+// maxIntrospectionResponseSize limita la respuesta del endpoint de introspección que se lee en memoria
+const maxIntrospectionResponseSize = 1 << 20
+
+func NewIntrospectionAuthenticator() *IntrospectionAuthenticator {
+	return &IntrospectionAuthenticator{
+		Url:          goenvars.GetEnv("OAUTH_INTROSPECTION_URL", "http://localhost:8080/oauth/introspect"),
+		ClientId:     goenvars.GetEnv("OAUTH_CLIENT_ID", ""),
+		ClientSecret: goenvars.GetEnv("OAUTH_CLIENT_SECRET", ""),
+		Client: &http.Client{
+			Timeout: time.Duration(goenvars.GetEnvInt("OAUTH_INTROSPECTION_TIMEOUT", 5)) * time.Second,
+		},
+	}
+}
+
+func (a *IntrospectionAuthenticator) Authenticate(ctx context.Context, token string, auth definitions.RouteAuth) (principal any, err error) {
+	ctx, span := tracing.StartSpanIfRecording(ctx, "oauth introspection", tracing.SpanKindClient)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Url, strings.NewReader(form.Encode()))
+	if err != nil {
+		golog.Error(ctx, "Error creating introspection request:", err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if span != nil {
+		tracing.Inject(ctx, req.Header)
+	}
+
+	if a.ClientId != "" {
+		req.SetBasicAuth(url.QueryEscape(a.ClientId), url.QueryEscape(a.ClientSecret))
+	}
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// los errores del endpoint (caído, credenciales del cliente inválidas, respuesta inválida) son
+	// fallas del servidor, no del token del usuario, por eso responden 502/503 y no 401
+	resp, err := client.Do(req)
+	if err != nil {
+		golog.Error(ctx, "Error making introspection request:", err)
+		return nil, newAuthError(http.StatusServiceUnavailable, "Authentication service unavailable")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIntrospectionResponseSize+1))
+	if err != nil {
+		golog.Error(ctx, "Error reading introspection response:", err)
+		return nil, newAuthError(http.StatusServiceUnavailable, "Authentication service unavailable")
+	}
+
+	if len(body) > maxIntrospectionResponseSize {
+		golog.Error(ctx, "Introspection response too large")
+		return nil, newAuthError(http.StatusBadGateway, "Invalid authentication service response")
+	}
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		golog.Error(ctx, "Introspection endpoint unavailable:", resp.Status)
+		return nil, newAuthError(http.StatusServiceUnavailable, "Authentication service unavailable")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		golog.Error(ctx, "Error response from introspection endpoint:", resp.Status)
+		return nil, newAuthError(http.StatusBadGateway, "Invalid authentication service response")
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(body, &result); err != nil {
+		golog.Error(ctx, "Error decoding introspection response:", err)
+		return nil, newAuthError(http.StatusBadGateway, "Invalid authentication service response")
+	}
+
+	if active, _ := result["active"].(bool); !active {
+		return nil, newAuthError(http.StatusUnauthorized, "Inactive token")
+	}
+
+	if auth.Permission != "" && !hasScope(result["scope"], auth) {
+		return nil, newAuthError(http.StatusForbidden, "Insufficient scope")
+	}
+
+	return result, nil
+}
+
+func hasScope(rawScope any, auth definitions.RouteAuth) bool {
+	scope, _ := rawScope.(string)
+
+	for _, s := range strings.Fields(scope) {
+		if s == auth.Permission {
+			return true
+		}
+
+		if auth.App != "" && s == auth.App+":"+auth.Permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newAuthError(statusCode int, message string) *HTTPError {
+	return &HTTPError{
+		Code:   statusCode,
+		Status: fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Body:   []byte(goerrors.NewGError(message, statusCode, nil, nil).ToJson()),
+	}
+}
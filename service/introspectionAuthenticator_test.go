@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "goroutes-service")
+	if err != nil {
+		panic(err)
+	}
+
+	golog.Init(map[string]db.DbConnection{}, golog.WithFileDriver(filepath.Join(dir, "service.log"), false))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newIntrospectionServer responde la introspección de los tokens definidos en responses
+func newIntrospectionServer(t *testing.T, responses map[string]map[string]any) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "my+client" || secret != "s%3Acret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.PostFormValue("token_type_hint") != "access_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		response, exists := responses[r.PostFormValue("token")]
+		if !exists {
+			response = map[string]any{"active": false}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestAuthenticator(url string) *IntrospectionAuthenticator {
+	return &IntrospectionAuthenticator{
+		Url:          url,
+		ClientId:     "my client",
+		ClientSecret: "s:cret",
+		Client:       http.DefaultClient,
+	}
+}
+
+func statusCode(err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+
+	return 0
+}
+
+func TestIntrospectionActiveToken(t *testing.T) {
+	server := newIntrospectionServer(t, map[string]map[string]any{
+		"good": {"active": true, "sub": "user-1", "scope": "users:read orders"},
+	})
+	authenticator := newTestAuthenticator(server.URL)
+
+	principal, err := authenticator.Authenticate(context.Background(), "Bearer good", definitions.RouteAuth{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, ok := principal.(map[string]any)
+	if !ok || claims["sub"] != "user-1" {
+		t.Fatalf("expected introspection claims as principal, got %#v", principal)
+	}
+}
+
+func TestIntrospectionInactiveToken(t *testing.T) {
+	server := newIntrospectionServer(t, map[string]map[string]any{
+		"expired": {"active": false, "sub": "user-1"},
+		"no-flag": {"sub": "user-1"},
+	})
+	authenticator := newTestAuthenticator(server.URL)
+
+	for _, token := range []string{"expired", "no-flag", "unknown"} {
+		_, err := authenticator.Authenticate(context.Background(), token, definitions.RouteAuth{})
+		if statusCode(err) != http.StatusUnauthorized {
+			t.Fatalf("token %q: expected 401, got %v", token, err)
+		}
+	}
+}
+
+func TestIntrospectionClientAuthentication(t *testing.T) {
+	server := newIntrospectionServer(t, map[string]map[string]any{
+		"good": {"active": true},
+	})
+
+	authenticator := newTestAuthenticator(server.URL)
+	authenticator.ClientSecret = "wrong"
+
+	// las credenciales del cliente son configuración del servidor, no es culpa del usuario
+	_, err := authenticator.Authenticate(context.Background(), "good", definitions.RouteAuth{})
+	if statusCode(err) != http.StatusBadGateway {
+		t.Fatalf("expected 502 when the endpoint rejects the client, got %v", err)
+	}
+}
+
+func TestIntrospectionEndpointFailure(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	cases := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"connection refused", down.URL, http.StatusServiceUnavailable},
+		{"server error", newStatusServer(t, http.StatusInternalServerError, "{}").URL, http.StatusServiceUnavailable},
+		{"rate limited", newStatusServer(t, http.StatusTooManyRequests, "{}").URL, http.StatusServiceUnavailable},
+		{"invalid json", newStatusServer(t, http.StatusOK, "<html>").URL, http.StatusBadGateway},
+		{"too large", newStatusServer(t, http.StatusOK, `{"active":true,"pad":"`+strings.Repeat("x", maxIntrospectionResponseSize)+`"}`).URL, http.StatusBadGateway},
+	}
+
+	for _, c := range cases {
+		_, err := newTestAuthenticator(c.url).Authenticate(context.Background(), "good", definitions.RouteAuth{})
+		if statusCode(err) != c.status {
+			t.Fatalf("%s: expected %d, got %v", c.name, c.status, err)
+		}
+	}
+}
+
+func newStatusServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestIntrospectionScopes(t *testing.T) {
+	server := newIntrospectionServer(t, map[string]map[string]any{
+		"good":     {"active": true, "scope": "orders users:read"},
+		"no-scope": {"active": true},
+	})
+	authenticator := newTestAuthenticator(server.URL)
+
+	cases := []struct {
+		token  string
+		auth   definitions.RouteAuth
+		status int
+	}{
+		{"good", definitions.RouteAuth{Permission: "orders"}, 0},
+		{"good", definitions.RouteAuth{App: "users", Permission: "read"}, 0},
+		{"good", definitions.RouteAuth{App: "users", Permission: "write"}, http.StatusForbidden},
+		{"good", definitions.RouteAuth{Permission: "read"}, http.StatusForbidden},
+		{"good", definitions.RouteAuth{App: "orders", Permission: "read"}, http.StatusForbidden},
+		{"no-scope", definitions.RouteAuth{Permission: "orders"}, http.StatusForbidden},
+		{"no-scope", definitions.RouteAuth{}, 0},
+	}
+
+	for _, c := range cases {
+		_, err := authenticator.Authenticate(context.Background(), c.token, c.auth)
+		if statusCode(err) != c.status || (c.status == 0 && err != nil) {
+			t.Fatalf("token %q auth %+v: expected status %d, got %v", c.token, c.auth, c.status, err)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nemutagk/goerrors"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// Config define cómo se sirven los archivos
+type Config struct {
+	// Index es el archivo que se sirve para los directorios (y el fallback de SPA)
+	Index string
+	// SPA sirve el Index de la raíz cuando el archivo no existe y el path no tiene extensión,
+	// para que el router del frontend resuelva la ruta
+	SPA bool
+	// Browse muestra el listado de los directorios sin Index
+	Browse bool
+	// Precompressed sirve las variantes .br/.gz del archivo cuando existen y el cliente las acepta
+	Precompressed bool
+	// CacheControl define el header Cache-Control por extensión (ej. ".js"), DefaultCacheControl
+	// se usa para las extensiones sin regla
+	CacheControl        map[string]string
+	DefaultCacheControl string
+}
+
+// DefaultConfig sirve index.html, variantes precomprimidas y cache largo para los assets; el HTML
+// se revalida siempre para que un nuevo despliegue se vea de inmediato
+func DefaultConfig() Config {
+	return Config{
+		Index:         "index.html",
+		Precompressed: true,
+		CacheControl: map[string]string{
+			".html": "no-cache",
+			".json": "no-cache",
+		},
+		DefaultCacheControl: "public, max-age=3600",
+	}
+}
+
+// Mount sirve fsys bajo prefix, el Mount resultante se agrega a RouteGroup.Routes y hereda los
+// middlewares del grupo. Las descargas no se limitan con el Timeout del grupo
+func Mount(prefix string, fsys fs.FS, config Config) definitions.Mount {
+	return definitions.Mount{
+		Prefix:  prefix,
+		Handler: Handler(fsys, config),
+		Timeout: -1,
+	}
+}
+
+// Handler sirve los archivos de fsys (os.DirFS, embed.FS con fs.Sub, etc.) con soporte de Range,
+// If-None-Match e If-Modified-Since mediante http.ServeContent
+func Handler(fsys fs.FS, config Config) http.Handler {
+	return &fileHandler{fsys: fsys, config: config}
+}
+
+type fileHandler struct {
+	fsys   fs.FS
+	config Config
+	etags  sync.Map
+}
+
+// variantes precomprimidas en orden de preferencia
+var encodings = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+func (h *fileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		errorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if !fs.ValidPath(name) || hidden(name) {
+		errorResponse(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+	if err == nil && info.IsDir() {
+		// como http.FileServer, los directorios terminan en "/" para que funcionen los links relativos
+		if name != "." && !strings.HasSuffix(r.URL.Path, "/") {
+			redirect(w, r, path.Base(r.URL.Path)+"/")
+			return
+		}
+
+		if h.config.Index != "" {
+			index := path.Join(name, h.config.Index)
+			if indexInfo, err := fs.Stat(h.fsys, index); err == nil && !indexInfo.IsDir() {
+				h.serveFile(w, r, index, indexInfo)
+				return
+			}
+		}
+
+		if h.config.Browse {
+			h.listDirectory(w, r, name)
+			return
+		}
+
+		err = fs.ErrNotExist
+	}
+
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && h.config.SPA && h.config.Index != "" && path.Ext(name) == "" {
+			if indexInfo, err := fs.Stat(h.fsys, h.config.Index); err == nil && !indexInfo.IsDir() {
+				h.serveFile(w, r, h.config.Index, indexInfo)
+				return
+			}
+		}
+
+		if errors.Is(err, fs.ErrNotExist) {
+			errorResponse(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		errorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.serveFile(w, r, name, info)
+}
+
+func (h *fileHandler) serveFile(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) {
+	header := w.Header()
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("Cache-Control", h.cacheControl(name))
+
+	servedName := name
+	servedInfo := info
+	if h.config.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+
+		accepted := acceptedEncodings(r.Header.Get("Accept-Encoding"))
+		for _, variant := range encodings {
+			if !accepted[variant.encoding] {
+				continue
+			}
+
+			if variantInfo, err := fs.Stat(h.fsys, name+variant.extension); err == nil && !variantInfo.IsDir() {
+				servedName = name + variant.extension
+				servedInfo = variantInfo
+				header.Set("Content-Encoding", variant.encoding)
+				break
+			}
+		}
+	}
+
+	file, err := h.fsys.Open(servedName)
+	if err != nil {
+		errorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			errorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if header.Get("ETag") == "" {
+		if etag, err := h.etag(servedName, servedInfo, content); err == nil {
+			header.Set("ETag", etag)
+		}
+	}
+
+	http.ServeContent(w, r, name, servedInfo.ModTime(), content)
+}
+
+// etag calcula el hash del archivo una vez por versión (nombre, tamaño y fecha), embed.FS no
+// tiene fecha de modificación por lo que Last-Modified no sirve para revalidar
+func (h *fileHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	key := name + "|" + strconv.FormatInt(info.Size(), 10) + "|" + info.ModTime().Format(time.RFC3339Nano)
+	if etag, ok := h.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	h.etags.Store(key, etag)
+
+	return etag, nil
+}
+
+func (h *fileHandler) cacheControl(name string) string {
+	if value, ok := h.config.CacheControl[strings.ToLower(path.Ext(name))]; ok {
+		return value
+	}
+
+	return h.config.DefaultCacheControl
+}
+
+func (h *fileHandler) listDirectory(w http.ResponseWriter, r *http.Request, name string) {
+	entries, err := fs.ReadDir(h.fsys, name)
+	if err != nil {
+		errorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if strings.HasPrefix(entryName, ".") {
+			continue
+		}
+
+		if entry.IsDir() {
+			entryName += "/"
+		}
+
+		link := url.URL{Path: entryName}
+		builder.WriteString("<a href=\"" + html.EscapeString(link.String()) + "\">" + html.EscapeString(entryName) + "</a>\n")
+	}
+	builder.WriteString("</pre>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	io.WriteString(w, builder.String())
+}
+
+// hidden evita servir archivos y directorios ocultos (ej. .git, .env) salvo .well-known
+func hidden(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") && segment != "." && segment != ".well-known" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func acceptedEncodings(acceptEncoding string) map[string]bool {
+	accepted := map[string]bool{}
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.TrimSpace(key) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		accepted[name] = quality > 0
+	}
+
+	return accepted
+}
+
+func redirect(w http.ResponseWriter, r *http.Request, target string) {
+	if query := r.URL.RawQuery; query != "" {
+		target += "?" + query
+	}
+
+	w.Header().Set("Location", target)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+func errorResponse(w http.ResponseWriter, message string, statusCode int) {
+	gErr := goerrors.NewGError(message, statusCode, nil, nil)
+
+	// los headers de cache del archivo no aplican al error
+	w.Header().Del("Cache-Control")
+	w.Header().Del("Content-Encoding")
+	w.Header().Del("ETag")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(gErr.GetStatusCode())
+	w.Write([]byte(gErr.ToJson()))
+}
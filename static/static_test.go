@@ -0,0 +1,247 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var staticModified = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func testFS() fstest.MapFS {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content), ModTime: staticModified}
+	}
+
+	return fstest.MapFS{
+		"index.html":                  file("<h1>app</h1>"),
+		"app.js":                      file("console.log('app')"),
+		"app.js.br":                   file("br-bytes"),
+		"app.js.gz":                   file("gz-bytes"),
+		"style.css":                   file("body{}"),
+		"style.css.gz":                file("css-gz-bytes"),
+		"docs/index.html":             file("<h1>docs</h1>"),
+		"docs/.git/config":            file("[core]"),
+		".env":                        file("SECRET=1"),
+		".well-known/security.txt":    file("contact: security@example.com"),
+		".well-known/.hidden":         file("hidden"),
+		"assets/.DS_Store":            file("binary"),
+		"assets/logo.svg":             file("<svg/>"),
+		"empty/.keep":                 file(""),
+		"nested/deep/page/index.html": file("<h1>deep</h1>"),
+	}
+}
+
+func serveStatic(handler http.Handler, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	return rec
+}
+
+func TestHiddenAndTraversal(t *testing.T) {
+	handler := Handler(testFS(), DefaultConfig())
+
+	cases := []struct {
+		target string
+		status int
+	}{
+		{"/.env", http.StatusNotFound},
+		{"/docs/.git/config", http.StatusNotFound},
+		{"/assets/.DS_Store", http.StatusNotFound},
+		{"/.well-known/.hidden", http.StatusNotFound},
+		{"/.well-known/security.txt", http.StatusOK},
+		{"/assets/./../.env", http.StatusNotFound},
+		{"/%2e%2e/%2e%2e/etc/passwd", http.StatusNotFound},
+		{"/assets/..%2f..%2f.env", http.StatusNotFound},
+		{"/assets/../app.js", http.StatusOK},
+		{"/missing.txt", http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		rec := serveStatic(handler, http.MethodGet, c.target, nil)
+		if rec.Code != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.target, c.status, rec.Code)
+		}
+
+		if c.status == http.StatusNotFound && (rec.Header().Get("Cache-Control") != "" || rec.Header().Get("ETag") != "") {
+			t.Fatalf("%s: the error must not have cache headers", c.target)
+		}
+	}
+
+	// el path sin limpiar tampoco debe salir de fsys
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.URL.Path = "../../.env"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a raw traversal path, got %d", rec.Code)
+	}
+}
+
+func TestDirectories(t *testing.T) {
+	handler := Handler(testFS(), DefaultConfig())
+
+	if rec := serveStatic(handler, http.MethodGet, "/docs?lang=es", nil); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "docs/?lang=es" {
+		t.Fatalf("expected a redirect to docs/, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	if rec := serveStatic(handler, http.MethodGet, "/docs/", nil); rec.Code != http.StatusOK || rec.Body.String() != "<h1>docs</h1>" {
+		t.Fatalf("expected the directory index, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	if rec := serveStatic(handler, http.MethodGet, "/empty/", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a directory without index, got %d", rec.Code)
+	}
+
+	config := DefaultConfig()
+	config.Browse = true
+	rec := serveStatic(Handler(testFS(), config), http.MethodGet, "/assets/", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n<a href=\"logo.svg\">logo.svg</a>\n</pre>\n" {
+		t.Fatalf("expected the listing without hidden files, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	if rec := serveStatic(handler, http.MethodPost, "/app.js", nil); rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Fatalf("expected 405 with Allow, got %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestSPAFallback(t *testing.T) {
+	config := DefaultConfig()
+	config.SPA = true
+	handler := Handler(testFS(), config)
+
+	cases := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/dashboard", http.StatusOK, "<h1>app</h1>"},
+		{"/users/42/edit", http.StatusOK, "<h1>app</h1>"},
+		{"/nested/deep/page/", http.StatusOK, "<h1>deep</h1>"},
+		{"/missing.js", http.StatusNotFound, ""},
+		{"/.env", http.StatusNotFound, ""},
+		{"/empty/", http.StatusOK, "<h1>app</h1>"},
+	}
+
+	for _, c := range cases {
+		rec := serveStatic(handler, http.MethodGet, c.target, nil)
+		if rec.Code != c.status || (c.body != "" && rec.Body.String() != c.body) {
+			t.Fatalf("%s: expected %d %q, got %d %q", c.target, c.status, c.body, rec.Code, rec.Body.String())
+		}
+
+		if c.status == http.StatusOK && rec.Header().Get("Cache-Control") != "no-cache" {
+			t.Fatalf("%s: the index must be revalidated, got %q", c.target, rec.Header().Get("Cache-Control"))
+		}
+	}
+
+	if rec := serveStatic(Handler(testFS(), DefaultConfig()), http.MethodGet, "/dashboard", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without SPA, got %d", rec.Code)
+	}
+}
+
+func TestPrecompressed(t *testing.T) {
+	handler := Handler(testFS(), DefaultConfig())
+
+	cases := []struct {
+		target         string
+		acceptEncoding string
+		encoding       string
+		body           string
+	}{
+		{"/app.js", "gzip, br", "br", "br-bytes"},
+		{"/app.js", "gzip", "gzip", "gz-bytes"},
+		{"/app.js", "br;q=0, gzip;q=0.5", "gzip", "gz-bytes"},
+		{"/app.js", "br;q=0, gzip;q=0", "", "console.log('app')"},
+		{"/app.js", "", "", "console.log('app')"},
+		{"/style.css", "br, gzip", "gzip", "css-gz-bytes"},
+		{"/style.css", "br", "", "body{}"},
+	}
+
+	etags := map[string]string{}
+	for _, c := range cases {
+		rec := serveStatic(handler, http.MethodGet, c.target, map[string]string{"Accept-Encoding": c.acceptEncoding})
+
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != c.encoding || rec.Body.String() != c.body {
+			t.Fatalf("%s %q: expected %q %q, got %d %q %q", c.target, c.acceptEncoding, c.encoding, c.body, rec.Code, rec.Header().Get("Content-Encoding"), rec.Body.String())
+		}
+
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("%s %q: expected Vary Accept-Encoding", c.target, c.acceptEncoding)
+		}
+
+		if contentType := rec.Header().Get("Content-Type"); contentType == "" || contentType == "application/gzip" {
+			t.Fatalf("%s %q: expected the Content-Type of the original file, got %q", c.target, c.acceptEncoding, contentType)
+		}
+
+		// cada variante tiene su propio ETag
+		key := c.target + "|" + c.encoding
+		if etag, exists := etags[key]; exists && etag != rec.Header().Get("ETag") {
+			t.Fatalf("%s %q: the ETag of the variant changed", c.target, c.acceptEncoding)
+		}
+		etags[key] = rec.Header().Get("ETag")
+	}
+
+	if etags["/app.js|br"] == etags["/app.js|gzip"] || etags["/app.js|gzip"] == etags["/app.js|"] {
+		t.Fatalf("expected a different ETag for each variant, got %v", etags)
+	}
+
+	config := DefaultConfig()
+	config.Precompressed = false
+	rec := serveStatic(Handler(testFS(), config), http.MethodGet, "/app.js", map[string]string{"Accept-Encoding": "br, gzip"})
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "console.log('app')" {
+		t.Fatalf("expected the original file without Precompressed, got %q", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestETagRevalidation(t *testing.T) {
+	handler := Handler(testFS(), DefaultConfig())
+
+	first := serveStatic(handler, http.MethodGet, "/app.js", nil)
+	etag := first.Header().Get("ETag")
+	if etag == "" || first.Header().Get("Last-Modified") != staticModified.Format(http.TimeFormat) {
+		t.Fatalf("expected ETag and Last-Modified, got %q %q", etag, first.Header().Get("Last-Modified"))
+	}
+
+	if first.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Fatalf("expected the default Cache-Control, got %q", first.Header().Get("Cache-Control"))
+	}
+
+	cases := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		status  int
+	}{
+		{"if-none-match", http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"if-none-match head", http.MethodHead, map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"if-none-match list", http.MethodGet, map[string]string{"If-None-Match": `"other", ` + etag}, http.StatusNotModified},
+		{"if-none-match changed", http.MethodGet, map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"if-modified-since", http.MethodGet, map[string]string{"If-Modified-Since": staticModified.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"if-modified-since older", http.MethodGet, map[string]string{"If-Modified-Since": staticModified.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK},
+		{"variant etag", http.MethodGet, map[string]string{"If-None-Match": etag, "Accept-Encoding": "gzip"}, http.StatusOK},
+		{"range", http.MethodGet, map[string]string{"Range": "bytes=0-6"}, http.StatusPartialContent},
+	}
+
+	for _, c := range cases {
+		rec := serveStatic(handler, c.method, "/app.js", c.headers)
+		if rec.Code != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.status, rec.Code)
+		}
+
+		if c.status == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Fatalf("%s: 304 must not have body", c.name)
+		}
+	}
+
+	if rec := serveStatic(handler, http.MethodGet, "/app.js", map[string]string{"Range": "bytes=0-6"}); rec.Body.String() != "console" {
+		t.Fatalf("expected the requested range, got %q", rec.Body.String())
+	}
+}
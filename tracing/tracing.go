@@ -0,0 +1,365 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nemutagk/goenvars"
+)
+
+const TRACEPARENT_HEADER = "traceparent"
+const TRACESTATE_HEADER = "tracestate"
+
+type SpanKind string
+
+const (
+	SpanKindServer   SpanKind = "server"
+	SpanKindClient   SpanKind = "client"
+	SpanKindInternal SpanKind = "internal"
+)
+
+// SpanContext es la parte del span que se propaga entre servicios (W3C Trace Context)
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Sampled    bool
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// ParseTraceparent interpreta el header traceparent ("00-<trace-id>-<span-id>-<flags>")
+func ParseTraceparent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+
+	// la versión 00 define exactamente 4 campos, versiones futuras pueden agregar más
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+
+	return sc, true
+}
+
+// Traceparent genera el valor del header traceparent del span
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// SpanData es la información exportada de un span terminado
+type SpanData struct {
+	Name         string         `json:"name"`
+	Kind         SpanKind       `json:"kind"`
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	TraceState   string         `json:"trace_state,omitempty"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	DurationMs   float64        `json:"duration_ms"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Status       string         `json:"status"`
+	StatusText   string         `json:"status_message,omitempty"`
+}
+
+type Span struct {
+	mu         sync.Mutex
+	name       string
+	kind       SpanKind
+	context    SpanContext
+	parentId   [8]byte
+	start      time.Time
+	attributes map[string]any
+	status     string
+	statusText string
+	ended      bool
+}
+
+// los métodos de Span aceptan un span nil (ver StartSpanIfRecording) y no hacen nada
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.context
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attributes[key] = value
+}
+
+// SetError marca el span como fallido
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = "error"
+	s.statusText = err.Error()
+}
+
+// End termina el span y lo envía al exporter si está muestreado
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+
+	end := time.Now()
+	data := SpanData{
+		Name:       s.name,
+		Kind:       s.kind,
+		TraceID:    hex.EncodeToString(s.context.TraceID[:]),
+		SpanID:     hex.EncodeToString(s.context.SpanID[:]),
+		TraceState: s.context.TraceState,
+		Start:      s.start,
+		End:        end,
+		DurationMs: float64(end.Sub(s.start).Microseconds()) / 1000,
+		Attributes: s.attributes,
+		Status:     s.status,
+		StatusText: s.statusText,
+	}
+	if s.parentId != [8]byte{} {
+		data.ParentSpanID = hex.EncodeToString(s.parentId[:])
+	}
+	s.mu.Unlock()
+
+	if s.context.Sampled {
+		getExporter().Export(data)
+	}
+}
+
+// Exporter recibe los spans terminados
+type Exporter interface {
+	Export(span SpanData)
+}
+
+type noopExporter struct{}
+
+func (noopExporter) Export(span SpanData) {}
+
+// StdoutExporter escribe cada span como una línea JSON, pensado para desarrollo local
+type StdoutExporter struct {
+	mu     sync.Mutex
+	Writer io.Writer
+}
+
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	return &StdoutExporter{Writer: w}
+}
+
+func (e *StdoutExporter) Export(span SpanData) {
+	line, err := json.Marshal(span)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Writer.Write(append(line, '\n'))
+}
+
+var (
+	exporterMu sync.RWMutex
+	exporter   Exporter
+)
+
+// SetExporter define el exporter de spans, si no se define se elige con GOROUTES_TRACING_EXPORTER
+// ("stdout" o vacío para no exportar)
+func SetExporter(e Exporter) {
+	exporterMu.Lock()
+	defer exporterMu.Unlock()
+
+	exporter = e
+}
+
+func getExporter() Exporter {
+	exporterMu.RLock()
+	e := exporter
+	exporterMu.RUnlock()
+
+	if e != nil {
+		return e
+	}
+
+	exporterMu.Lock()
+	defer exporterMu.Unlock()
+
+	if exporter == nil {
+		switch goenvars.GetEnv("GOROUTES_TRACING_EXPORTER", "") {
+		case "stdout":
+			exporter = NewStdoutExporter(os.Stdout)
+		default:
+			exporter = noopExporter{}
+		}
+	}
+
+	return exporter
+}
+
+// exporterConfigured indica si los spans se envían a algún lugar
+func exporterConfigured() bool {
+	_, noop := getExporter().(noopExporter)
+	return !noop
+}
+
+type spanContextKey struct{}
+type remoteContextKey struct{}
+
+// SpanFromContext obtiene el span activo del contexto
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext guarda el span recibido de otro servicio para usarlo como padre
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteContextKey{}, sc)
+}
+
+// Extract lee traceparent/tracestate de los headers de una petición entrante
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, ok := ParseTraceparent(header.Get(TRACEPARENT_HEADER))
+	if !ok {
+		return ctx
+	}
+
+	sc.TraceState = header.Get(TRACESTATE_HEADER)
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject escribe traceparent/tracestate del span activo en los headers de una petición saliente
+func Inject(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	header.Set(TRACEPARENT_HEADER, span.context.Traceparent())
+	if span.context.TraceState != "" {
+		header.Set(TRACESTATE_HEADER, span.context.TraceState)
+	}
+}
+
+// StartSpan crea un span hijo del span activo (o del recibido de otro servicio), si no existe
+// ninguno se inicia una nueva traza muestreada con GOROUTES_TRACING_SAMPLE_RATIO
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := &Span{
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: map[string]any{},
+		status:     "ok",
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.context = parent.context
+		span.parentId = parent.context.SpanID
+	} else if remote, ok := ctx.Value(remoteContextKey{}).(SpanContext); ok && remote.IsValid() {
+		span.context = remote
+		span.parentId = remote.SpanID
+	} else {
+		rand.Read(span.context.TraceID[:])
+		span.context.Sampled = sampled()
+	}
+
+	rand.Read(span.context.SpanID[:])
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// StartSpanIfRecording igual que StartSpan pero si no hay exporter configurado o la traza no está
+// muestreada retorna el contexto sin cambios y un span nil, para que las operaciones de cliente no
+// creen spans ni envíen traceparent que nadie va a exportar
+func StartSpanIfRecording(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if !exporterConfigured() {
+		return ctx, nil
+	}
+
+	spanCtx, span := StartSpan(ctx, name, kind)
+	if !span.context.Sampled {
+		return ctx, nil
+	}
+
+	return spanCtx, span
+}
+
+func sampled() bool {
+	ratio := goenvars.GetEnvFloat("GOROUTES_TRACING_SAMPLE_RATIO", 1)
+	if ratio >= 1 {
+		return true
+	}
+
+	if ratio <= 0 {
+		return false
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return false
+	}
+
+	return float64(n.Int64()) < ratio*1_000_000
+}
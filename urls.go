@@ -0,0 +1,109 @@
+package goroutes
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+var (
+	routeNamesMu sync.RWMutex
+	routeNames   = map[string]string{}
+)
+
+// registerRouteNames guarda el patrón de las rutas con nombre, un nombre repetido con otro patrón
+// es un error de configuración y detiene el arranque igual que los patrones repetidos en
+// http.ServeMux. El mismo nombre con el mismo patrón se acepta para poder llamar LoadRoutes con
+// las mismas rutas en varios mux (ej. en tests)
+func registerRouteNames(routeList map[string]definitions.Route) {
+	routeNamesMu.Lock()
+	defer routeNamesMu.Unlock()
+
+	for path, route := range routeList {
+		routes := []definitions.Route{route}
+		if route.Group != nil {
+			routes = routes[:0]
+			for _, subRoute := range route.Group {
+				routes = append(routes, subRoute)
+			}
+		}
+
+		for _, r := range routes {
+			if r.Name == "" {
+				continue
+			}
+
+			// las rutas de un VersionedGroup se nombran con su versión (ej. "v2.users.show")
+			name := r.Name
+			if r.Version != "" {
+				name = r.Version + "." + name
+			}
+
+			if existing, exists := routeNames[name]; exists && existing != r.Pattern {
+				panic("goroutes: route name \"" + name + "\" already registered for " + existing + ", duplicated in " + path)
+			}
+
+			// la llave puede incluir el host del grupo, la URL se genera con el path
+			routeNames[name] = r.Pattern
+		}
+	}
+}
+
+// URL genera la URL de la ruta con nombre reemplazando sus parámetros ({id}, {path...}) y agrega
+// el query string. Retorna error si la ruta no existe o falta algún parámetro
+func URL(name string, params map[string]string, query url.Values) (string, error) {
+	routeNamesMu.RLock()
+	pattern, exists := routeNames[name]
+	routeNamesMu.RUnlock()
+
+	if !exists {
+		return "", errors.New("route name not found: " + name)
+	}
+
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+
+		param := segment[1 : len(segment)-1]
+		if param == "$" {
+			segments[i] = ""
+			continue
+		}
+
+		wildcard := strings.HasSuffix(param, "...")
+		param = strings.TrimSuffix(param, "...")
+
+		value, ok := params[param]
+		if !ok || (value == "" && !wildcard) {
+			return "", errors.New("missing parameter \"" + param + "\" for route: " + name)
+		}
+
+		if !wildcard {
+			segments[i] = url.PathEscape(value)
+			continue
+		}
+
+		// el comodín final puede contener varios segmentos, se escapa cada uno
+		parts := strings.Split(value, "/")
+		for j, part := range parts {
+			parts[j] = url.PathEscape(part)
+		}
+		segments[i] = strings.Join(parts, "/")
+	}
+
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	return path, nil
+}
@@ -0,0 +1,292 @@
+package goroutes
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nemutagk/godb/definitions/db"
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+type versionItem struct {
+	key  string
+	item interface{}
+}
+
+// versionedRoutes expande un VersionedGroup en las rutas con el prefijo de cada versión y las
+// rutas sin versión, que guardan en Versions la ruta de cada versión para elegirla en cada petición
+func versionedRoutes(vg definitions.VersionedGroup, parentPath string, parentMiddleware []definitions.Middleware, parentTimeout time.Duration) map[string]definitions.Route {
+	allRoutes := map[string]definitions.Route{}
+
+	if len(vg.Versions) == 0 {
+		golog.Error(context.Background(), "Versioned group without versions:", vg.Prefix)
+		return allRoutes
+	}
+
+	defaultVersion := vg.Default
+	if defaultVersion == "" {
+		defaultVersion = vg.Versions[len(vg.Versions)-1].Name
+	}
+
+	header := vg.Header
+	if header == "" {
+		header = goenvars.GetEnv("GOROUTES_API_VERSION_HEADER", "API-Version")
+	}
+
+	names := make([]string, 0, len(vg.Versions))
+	for _, version := range vg.Versions {
+		names = append(names, version.Name)
+	}
+
+	selector := &definitions.VersionSelector{Header: header, Default: defaultVersion, Names: names}
+
+	unversioned := map[string]definitions.Route{}
+	var items []versionItem
+
+	for _, version := range vg.Versions {
+		items = inheritRoutes(items, version)
+
+		routes := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			routes = append(routes, item.item)
+		}
+
+		group := definitions.RouteGroup{
+			Prefix:      strings.TrimSuffix(vg.Prefix, "/") + "/" + version.Name,
+			Middlewares: vg.Middlewares,
+			Timeout:     vg.Timeout,
+			Routes:      routes,
+		}
+
+		for path, route := range checkRoute(group, parentPath, slices.Clone(parentMiddleware), parentTimeout) {
+			allRoutes[path] = withVersion(route, version)
+		}
+
+		group.Prefix = vg.Prefix
+		for path, route := range checkRoute(group, parentPath, slices.Clone(parentMiddleware), parentTimeout) {
+			placeholder, exists := unversioned[path]
+			if !exists {
+				// los middlewares del grupo (y los del padre que agrega checkRoute) atienden las
+				// respuestas de versión no soportada o sin la ruta
+				placeholder = definitions.Route{
+					Pattern:         route.Pattern,
+					Versions:        map[string]definitions.Route{},
+					VersionSelector: selector,
+					Middlewares:     cloneMiddlewares(vg.Middlewares),
+				}
+			}
+
+			placeholder.Versions[version.Name] = withVersion(route, version)
+			unversioned[path] = placeholder
+		}
+	}
+
+	for path, route := range unversioned {
+		if _, exists := allRoutes[path]; exists {
+			golog.Error(context.Background(), "Route already exists:", path)
+			continue
+		}
+
+		allRoutes[path] = route
+	}
+
+	return allRoutes
+}
+
+// inheritRoutes reemplaza las rutas heredadas que la versión redefine, agrega las nuevas y quita
+// las indicadas en Remove
+func inheritRoutes(items []versionItem, version definitions.Version) []versionItem {
+	removed := map[string]bool{}
+	for _, entry := range version.Remove {
+		removed[normalizeVersionKey(entry)] = true
+	}
+
+	inherited := make([]versionItem, 0, len(items)+len(version.Routes))
+	for _, item := range items {
+		if !removed[item.key] {
+			inherited = append(inherited, item)
+		}
+	}
+
+	for _, item := range version.Routes {
+		key := versionKey(item)
+
+		replaced := false
+		for i := range inherited {
+			if inherited[i].key == key {
+				inherited[i].item = item
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			inherited = append(inherited, versionItem{key: key, item: item})
+		}
+	}
+
+	return inherited
+}
+
+func versionKey(item interface{}) string {
+	switch value := item.(type) {
+	case definitions.Route:
+		return strings.ToUpper(value.Method) + " " + preparePath(value.Path, "/")
+	case definitions.RouteGroup:
+		return preparePath(value.Prefix, "/")
+	case definitions.Mount:
+		return preparePath(value.Prefix, "/")
+	case definitions.VersionedGroup:
+		return preparePath(value.Prefix, "/")
+	}
+
+	return ""
+}
+
+func normalizeVersionKey(entry string) string {
+	method, path, found := strings.Cut(strings.TrimSpace(entry), " ")
+	if !found {
+		return preparePath(method, "/")
+	}
+
+	return strings.ToUpper(method) + " " + preparePath(strings.TrimSpace(path), "/")
+}
+
+// withVersion asigna la versión a la ruta y a sus métodos, y agrega los headers de deprecación a
+// sus acciones
+func withVersion(route definitions.Route, version definitions.Version) definitions.Route {
+	route.Version = version.Name
+	if route.Action != nil {
+		route.Action = versionAction(route.Action, version)
+	}
+
+	// la ruta original también queda dentro de Group, por lo que no se recorre recursivamente
+	for method, subRoute := range route.Group {
+		subRoute.Version = version.Name
+		subRoute.Action = versionAction(subRoute.Action, version)
+		route.Group[method] = subRoute
+	}
+
+	return route
+}
+
+func versionAction(action http.HandlerFunc, version definitions.Version) http.HandlerFunc {
+	if version.Deprecation.IsZero() && version.Sunset.IsZero() && version.Link == "" {
+		return action
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !version.Deprecation.IsZero() {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(version.Deprecation.Unix(), 10))
+		}
+
+		if !version.Sunset.IsZero() {
+			w.Header().Set("Sunset", version.Sunset.UTC().Format(http.TimeFormat))
+		}
+
+		if version.Link != "" {
+			w.Header().Add("Link", "<"+version.Link+">; rel=\"deprecation\"")
+		}
+
+		action(w, r)
+	}
+}
+
+// versionDispatch atiende el path sin versión con la ruta de la versión solicitada
+func versionDispatch(route definitions.Route, dbListConn map[string]db.DbConnection, methodNotAllowed http.HandlerFunc) http.HandlerFunc {
+	handlers := map[string]http.HandlerFunc{}
+	for name, versionRoute := range route.Versions {
+		handlers[name] = applyMiddleware(versionRoute, dbListConn, methodNotAllowed)
+	}
+
+	selector := definitions.VersionSelector{Header: "API-Version"}
+	if route.VersionSelector != nil {
+		selector = *route.VersionSelector
+	}
+
+	// los errores pasan por los middlewares de la ruta (CORS, Recovery, logs de acceso)
+	mws := []definitions.Middleware{}
+	if route.Middlewares != nil {
+		mws = *route.Middlewares
+	}
+	unsupported := unmatchedAction(func() http.HandlerFunc { return unsupportedVersionHandler }, mws, dbListConn)
+	notFound := unmatchedAction(getNotFoundHandler, mws, dbListConn)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", selector.Header+", Accept")
+
+		version := requestVersion(r, selector.Header)
+		if version == "" {
+			version = selector.Default
+		}
+
+		name, known := resolveVersion(version, selector.Names)
+		if !known {
+			unsupported(w, r)
+			return
+		}
+
+		handler, exists := handlers[name]
+		if !exists {
+			// la versión existe en el grupo pero no tiene esta ruta
+			notFound(w, r)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func unsupportedVersionHandler(w http.ResponseWriter, r *http.Request) {
+	respondError(w, r, "Unsupported API version", http.StatusBadRequest)
+}
+
+func cloneMiddlewares(mws *[]definitions.Middleware) *[]definitions.Middleware {
+	if mws == nil {
+		return nil
+	}
+
+	cloned := slices.Clone(*mws)
+	return &cloned
+}
+
+// requestVersion obtiene la versión del header configurado o del parámetro version del Accept
+func requestVersion(r *http.Request, header string) string {
+	if version := strings.TrimSpace(r.Header.Get(header)); version != "" {
+		return version
+	}
+
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			_, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil {
+				continue
+			}
+
+			if version := params["version"]; version != "" {
+				return version
+			}
+		}
+	}
+
+	return ""
+}
+
+// resolveVersion acepta el nombre de la versión con o sin "v" (2 o v2)
+func resolveVersion(version string, names []string) (string, bool) {
+	candidates := []string{version, "v" + version, strings.TrimPrefix(strings.ToLower(version), "v")}
+	for _, name := range names {
+		if slices.Contains(candidates, name) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
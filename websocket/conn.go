@@ -0,0 +1,386 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Códigos de cierre (RFC 6455 sección 7.4.1)
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseAbnormalClosure  = 1006
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+// ErrClosed se retorna al escribir después de enviar el frame de cierre
+var ErrClosed = errors.New("websocket: connection closed")
+
+// CloseError indica que la conexión se cerró con el código y la razón recibidos o enviados
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Reason
+}
+
+// Conn es una conexión WebSocket del lado del servidor. ReadMessage debe llamarse desde una sola
+// goroutine, las escrituras son seguras entre goroutines
+type Conn struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	config      Config
+	subprotocol string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	writeMu   sync.Mutex
+	closeSent bool
+	closeOnce sync.Once
+}
+
+// defaultReadLimit es el tamaño máximo de un mensaje cuando Config.ReadLimit no se define, sin
+// límite el largo del frame (controlado por el cliente) definiría cuánta memoria se reserva
+const defaultReadLimit = 1 << 20
+
+func newConn(parent context.Context, netConn net.Conn, reader *bufio.Reader, subprotocol string, config Config) *Conn {
+	ctx, cancel := context.WithCancel(parent)
+
+	if config.ReadLimit <= 0 {
+		config.ReadLimit = defaultReadLimit
+	}
+
+	c := &Conn{
+		conn:        netConn,
+		reader:      reader,
+		config:      config,
+		subprotocol: subprotocol,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	if config.PingInterval > 0 {
+		go c.keepalive()
+	}
+
+	return c
+}
+
+// Context se cancela cuando la conexión se cierra
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Subprotocol es el subprotocolo negociado en el handshake
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage espera el siguiente mensaje completo (une los fragmentos) y responde los pings.
+// Cuando el cliente cierra la conexión retorna *CloseError
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var messageType MessageType
+	var message []byte
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, c.fail(err)
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			return 0, nil, c.receiveClose(payload)
+		case opText, opBinary:
+			if messageType != 0 {
+				return 0, nil, c.fail(&CloseError{Code: CloseProtocolError, Reason: "expected continuation frame"})
+			}
+			messageType = MessageType(opcode)
+			message = payload
+		case opContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(&CloseError{Code: CloseProtocolError, Reason: "unexpected continuation frame"})
+			}
+			message = append(message, payload...)
+		default:
+			return 0, nil, c.fail(&CloseError{Code: CloseProtocolError, Reason: "unknown opcode"})
+		}
+
+		if int64(len(message)) > c.config.ReadLimit {
+			return 0, nil, c.fail(&CloseError{Code: CloseMessageTooBig, Reason: "message too big"})
+		}
+
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(&CloseError{Code: CloseInvalidPayload, Reason: "invalid utf-8"})
+			}
+
+			return messageType, message, nil
+		}
+	}
+}
+
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return errors.New("websocket: invalid message type")
+	}
+
+	return c.writeFrame(byte(messageType), data)
+}
+
+// WriteJSON envía el valor como mensaje de texto en JSON
+func (c *Conn) WriteJSON(value any) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return c.writeFrame(opText, content)
+}
+
+// Close envía el frame de cierre con el código y la razón indicados y cierra la conexión
+func (c *Conn) Close(code int, reason string) error {
+	err := c.sendClose(code, reason)
+	c.shutdown()
+
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	if c.config.PingInterval > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.config.PingInterval + c.config.PongTimeout))
+	}
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	// sin extensiones negociadas los bits RSV deben ser 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "reserved bits set"}
+	}
+
+	// los frames del cliente siempre van enmascarados (RFC 6455 sección 5.1)
+	if !masked {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "unmasked client frame"}
+	}
+
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(c.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(c.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+
+		// el bit más significativo del largo de 64 bits debe ser 0 (RFC 6455 sección 5.2)
+		if length>>63 != 0 {
+			return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "invalid payload length"}
+		}
+	}
+
+	if opcode >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "invalid control frame"}
+	}
+
+	// se valida antes de reservar memoria para el payload
+	if length > uint64(c.config.ReadLimit) {
+		return false, 0, nil, &CloseError{Code: CloseMessageTooBig, Reason: "message too big"}
+	}
+
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(c.reader, mask); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+
+	if opcode == opClose {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	if c.config.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteTimeout))
+	}
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func (c *Conn) sendClose(code int, reason string) error {
+	payload := []byte{}
+	if code != CloseNoStatusReceived && code != CloseAbnormalClosure {
+		payload = binary.BigEndian.AppendUint16(payload, uint16(code))
+		// el payload de un frame de control no puede superar 125 bytes
+		if len(reason) > 123 {
+			reason = reason[:123]
+		}
+		payload = append(payload, reason...)
+	}
+
+	return c.writeFrame(opClose, payload)
+}
+
+// receiveClose responde el cierre iniciado por el cliente con el mismo código
+func (c *Conn) receiveClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+
+	if len(payload) == 1 {
+		closeErr.Code = CloseProtocolError
+	} else if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+
+		if !validCloseCode(closeErr.Code) || !utf8.Valid(payload[2:]) {
+			closeErr.Code = CloseProtocolError
+		}
+	}
+
+	c.sendClose(closeErr.Code, "")
+	c.shutdown()
+
+	return closeErr
+}
+
+// fail cierra la conexión por un error de lectura, los errores de protocolo se informan al
+// cliente con el código correspondiente
+func (c *Conn) fail(err error) error {
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) {
+		c.sendClose(closeErr.Code, closeErr.Reason)
+	} else {
+		err = &CloseError{Code: CloseAbnormalClosure, Reason: err.Error()}
+	}
+
+	c.shutdown()
+	return err
+}
+
+func (c *Conn) shutdown() {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.conn.Close()
+	})
+}
+
+func (c *Conn) keepalive() {
+	ticker := time.NewTicker(c.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.writeFrame(opPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code >= 1000 && code <= 1011:
+		return code != 1004 && code != CloseNoStatusReceived && code != CloseAbnormalClosure
+	}
+
+	return false
+}
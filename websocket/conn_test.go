@@ -0,0 +1,160 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// newTestConn conecta un Conn del servidor con el extremo del cliente, lo que el servidor
+// escribe (ej. el frame de cierre) se descarta
+func newTestConn(t *testing.T, config Config) (*Conn, net.Conn) {
+	t.Helper()
+
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	conn := newConn(context.Background(), server, bufio.NewReader(server), "", config)
+
+	return conn, client
+}
+
+// clientFrame arma el header de un frame enmascarado con el largo indicado, si payload no es
+// nil se agrega la máscara y el payload enmascarado
+func clientFrame(opcode byte, length uint64, payload []byte) []byte {
+	frame := []byte{0x80 | opcode}
+
+	switch {
+	case length <= 125:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, length)
+	}
+
+	if payload == nil {
+		return frame
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	return frame
+}
+
+// readWith envía los bytes desde el cliente y retorna el resultado de ReadMessage
+func readWith(t *testing.T, config Config, data []byte) (MessageType, []byte, error) {
+	t.Helper()
+
+	conn, client := newTestConn(t, config)
+
+	go func() {
+		client.Write(data)
+		io.Copy(io.Discard, client)
+	}()
+
+	return conn.ReadMessage()
+}
+
+func closeCode(err error) int {
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code
+	}
+
+	return 0
+}
+
+func TestReadMessage(t *testing.T) {
+	messageType, message, err := readWith(t, Config{}, clientFrame(opText, 5, []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messageType != TextMessage || string(message) != "hello" {
+		t.Fatalf("expected text message hello, got %d %q", messageType, message)
+	}
+}
+
+func TestReadOversizedFrame(t *testing.T) {
+	cases := []struct {
+		name   string
+		config Config
+		length uint64
+	}{
+		{"default limit", Config{}, defaultReadLimit + 1},
+		{"huge length without limit", Config{}, 1 << 40},
+		{"configured limit", Config{ReadLimit: 10}, 11},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// solo se envía el header, el frame se rechaza antes de reservar el payload
+			_, _, err := readWith(t, c.config, clientFrame(opBinary, c.length, nil))
+			if closeCode(err) != CloseMessageTooBig {
+				t.Fatalf("expected close 1009, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadOversizedFragments(t *testing.T) {
+	data := append(clientFrame(opText, 6, []byte("012345")), clientFrame(opContinuation, 6, []byte("678901"))...)
+	// el primer fragmento no es el final
+	data[0] &^= 0x80
+
+	_, _, err := readWith(t, Config{ReadLimit: 10}, data)
+	if closeCode(err) != CloseMessageTooBig {
+		t.Fatalf("expected close 1009, got %v", err)
+	}
+}
+
+func TestReadMalformedFrame(t *testing.T) {
+	unmasked := clientFrame(opText, 2, []byte("hi"))
+	unmasked[1] &^= 0x80
+
+	reserved := clientFrame(opText, 2, []byte("hi"))
+	reserved[0] |= 0x40
+
+	largeControl := clientFrame(opPing, 126, nil)
+
+	fragmentedControl := clientFrame(opPing, 0, []byte{})
+	fragmentedControl[0] &^= 0x80
+
+	cases := []struct {
+		name string
+		data []byte
+		code int
+	}{
+		{"length msb set", clientFrame(opBinary, 1<<63, nil), CloseProtocolError},
+		{"unmasked", unmasked, CloseProtocolError},
+		{"reserved bits", reserved, CloseProtocolError},
+		{"large control frame", largeControl, CloseProtocolError},
+		{"fragmented control frame", fragmentedControl, CloseProtocolError},
+		{"unexpected continuation", clientFrame(opContinuation, 2, []byte("hi")), CloseProtocolError},
+		{"unknown opcode", clientFrame(0x3, 2, []byte("hi")), CloseProtocolError},
+		{"invalid utf-8", clientFrame(opText, 2, []byte{0xff, 0xfe}), CloseInvalidPayload},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := readWith(t, Config{}, c.data)
+			if closeCode(err) != c.code {
+				t.Fatalf("expected close %d, got %v", c.code, err)
+			}
+		})
+	}
+}
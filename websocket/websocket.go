@@ -0,0 +1,213 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Nemutagk/goenvars"
+	"github.com/Nemutagk/goerrors"
+	"github.com/Nemutagk/golog"
+	"github.com/Nemutagk/goroutes/definitions"
+)
+
+// GUID definido en RFC 6455 para calcular Sec-WebSocket-Accept
+const acceptGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Config define el handshake y los límites de la conexión
+type Config struct {
+	// AllowedOrigins son los orígenes aceptados en el handshake, "*" acepta cualquiera y vacío
+	// solo el mismo host de la petición. Las peticiones sin header Origin (clientes que no son
+	// navegadores) siempre se aceptan
+	AllowedOrigins []string
+	// Subprotocols son los subprotocolos soportados en orden de preferencia
+	Subprotocols []string
+	// ReadLimit es el tamaño máximo de un mensaje, al superarlo se cierra con 1009. Con 0 se usa
+	// 1 MiB
+	ReadLimit int64
+	// PingInterval es cada cuánto se envía un ping, 0 lo desactiva
+	PingInterval time.Duration
+	// PongTimeout es cuánto se espera algún frame del cliente después del ping antes de cerrar
+	PongTimeout time.Duration
+	// WriteTimeout es el tiempo máximo para escribir un frame
+	WriteTimeout time.Duration
+}
+
+// LoadConfigFromEnv genera la configuración a partir de GOROUTES_WS_*. Los orígenes no se toman
+// de CORS_ALLOW_ORIGIN: el "*" habitual en CORS permitiría a cualquier sitio abrir conexiones con
+// las cookies del usuario (cross-site WebSocket hijacking), sin GOROUTES_WS_ALLOWED_ORIGINS solo
+// se acepta el mismo host
+func LoadConfigFromEnv() Config {
+	return Config{
+		AllowedOrigins: splitList(goenvars.GetEnv("GOROUTES_WS_ALLOWED_ORIGINS", "")),
+		Subprotocols:   splitList(goenvars.GetEnv("GOROUTES_WS_SUBPROTOCOLS", "")),
+		ReadLimit:      int64(goenvars.GetEnvInt("GOROUTES_WS_READ_LIMIT", 1<<20)),
+		PingInterval:   time.Duration(goenvars.GetEnvInt("GOROUTES_WS_PING_INTERVAL", 30)) * time.Second,
+		PongTimeout:    time.Duration(goenvars.GetEnvInt("GOROUTES_WS_PONG_TIMEOUT", 30)) * time.Second,
+		WriteTimeout:   time.Duration(goenvars.GetEnvInt("GOROUTES_WS_WRITE_TIMEOUT", 10)) * time.Second,
+	}
+}
+
+// Handler atiende la conexión, al terminar la conexión se cierra con 1000 si sigue abierta
+type Handler func(conn *Conn, r *http.Request)
+
+// Route genera una ruta GET que hace el upgrade a WebSocket. La ruta pasa por los mismos
+// middlewares que las demás (auth, logs de acceso, métricas) antes del handshake y desactiva
+// el Timeout heredado del grupo
+func Route(path string, handler Handler, config Config) definitions.Route {
+	return definitions.Route{
+		Path:    path,
+		Method:  http.MethodGet,
+		Timeout: -1,
+		Action: func(w http.ResponseWriter, r *http.Request) {
+			conn, err := Upgrade(w, r, config)
+			if err != nil {
+				golog.Warning(r.Context(), "WebSocket upgrade failed:", err.Error())
+				return
+			}
+			defer conn.Close(CloseNormalClosure, "")
+
+			handler(conn, r)
+		},
+	}
+}
+
+// Upgrade valida el handshake (RFC 6455 sección 4.2) y toma la conexión. Si el handshake es
+// inválido responde el error HTTP correspondiente y retorna el error
+func Upgrade(w http.ResponseWriter, r *http.Request, config Config) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, handshakeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+
+	if r.ProtoMajor != 1 {
+		return nil, handshakeError(w, http.StatusHTTPVersionNotSupported, "WebSocket requires HTTP/1.1")
+	}
+
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		return nil, handshakeError(w, http.StatusUpgradeRequired, "WebSocket upgrade required")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(w, http.StatusUpgradeRequired, "Unsupported WebSocket version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, handshakeError(w, http.StatusBadRequest, "Invalid Sec-WebSocket-Key")
+	}
+
+	if !originAllowed(r, config.AllowedOrigins) {
+		return nil, handshakeError(w, http.StatusForbidden, "Origin not allowed")
+	}
+
+	subprotocol := selectSubprotocol(r, config.Subprotocols)
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, handshakeError(w, http.StatusInternalServerError, "Internal server error")
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n"
+	if subprotocol != "" {
+		response += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	response += "\r\n"
+
+	// el servidor puede haber dejado deadlines en la conexión, se manejan por frame
+	netConn.SetDeadline(time.Time{})
+	if config.WriteTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(config.WriteTimeout))
+	}
+
+	if _, err := brw.WriteString(response); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return newConn(r.Context(), netConn, brw.Reader, subprotocol, config), nil
+}
+
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGuid))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func handshakeError(w http.ResponseWriter, statusCode int, message string) error {
+	gErr := goerrors.NewGError(message, statusCode, nil, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(gErr.GetStatusCode())
+	w.Write([]byte(gErr.ToJson()))
+
+	return errors.New(message)
+}
+
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if len(allowed) == 0 {
+		// sin configuración solo se aceptan peticiones del mismo host
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}
+
+	for _, candidate := range allowed {
+		if candidate == "*" || strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, requested := range splitList(strings.Join(r.Header.Values("Sec-WebSocket-Protocol"), ",")) {
+		for _, candidate := range supported {
+			if requested == candidate {
+				return candidate
+			}
+		}
+	}
+
+	return ""
+}
+
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}